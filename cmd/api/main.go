@@ -9,8 +9,13 @@ import (
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/kevinhartarto/tasker/internal/consumer"
+	"github.com/kevinhartarto/tasker/internal/controllers"
 	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/delayqueue"
+	"github.com/kevinhartarto/tasker/internal/leader"
 	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/scheduler"
 	"github.com/kevinhartarto/tasker/internal/server"
 	"github.com/kevinhartarto/tasker/internal/utils"
 )
@@ -25,26 +30,50 @@ func main() {
 	redis := server.StartRedis()
 	app := server.TaskerHandler(gorm, *redis)
 
+	// Only the replica holding the leader lease dispatches reminders
+	elector := leader.NewElector(redis, gorm)
+	elector.Start()
+
+	// Reminder scheduler, with the optional delay queue for on time reminders
+	queue := delayqueue.NewQueue(redis)
+	reminder := controllers.InitReminderController(gorm, queue)
+	reminderScheduler := scheduler.NewScheduler(reminder, elector, queue)
+	reminderScheduler.Start()
+
+	// Acknowledgements reported back by downstream services
+	ackConsumer := consumer.NewAckConsumer(reminder)
+	ackConsumer.Start()
+
 	log.Info("Tasker starting...")
 	apiPort := utils.GetEnvOrDefault("PORT_API", "3030")
 	apiAddr := fmt.Sprintf(":%v", apiPort)
 
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+
 	log.Info("Running Tasker server", "Port", apiAddr)
 	utils.SendDesktopNotification("notify", "Tasker Running", "Tasker is now running")
-	if appErr := app.Listen(apiAddr); appErr != nil {
-		log.Error("Failed to start Tasker, exiting...")
-		closeApp(app, gorm)
-	}
-}
+	go func() {
+		if appErr := app.Listen(apiAddr); appErr != nil {
+			log.Error("Failed to start Tasker, exiting...", "message: ", appErr)
+			quit <- syscall.SIGTERM
+		}
+	}()
 
-func closeApp(app *fiber.App, gorm database.Database) {
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	closeApp(quit, app, gorm, reminder, reminderScheduler, elector, ackConsumer)
+}
 
+func closeApp(quit chan os.Signal, app *fiber.App, gorm database.Database, reminder controllers.ReminderController, reminderScheduler scheduler.Scheduler, elector leader.Elector, ackConsumer consumer.Consumer) {
 	<-quit // Wait for termination signal
 
 	log.Info("Shutting down tasker...")
 
+	// Stop dispatching reminders before the database goes away
+	reminderScheduler.Stop()
+	elector.Stop()
+	ackConsumer.Stop()
+	reminder.Close()
+
 	// Gracefully shut down Fiber
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -0,0 +1,242 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/recurrence"
+	"github.com/kevinhartarto/tasker/internal/utils"
+)
+
+type Store interface {
+
+	// Check if a calendar with the given name exists
+	Has(name string) bool
+
+	// Check if a date is excluded by the given calendar
+	// the date is taken as is, in its own time zone
+	Excluded(name string, date time.Time) bool
+
+	// Dates excluded by the given calendar
+	// from the date of from up to, not including, the date of to
+	ExcludedBetween(name string, from time.Time, to time.Time) []time.Time
+
+	// Working hours of the given calendar,
+	// WORKING_DAYS and WORKING_HOURS for an empty name
+	WorkingHours(name string) (recurrence.WorkingHours, bool)
+
+	// Reload the calendars from the database
+	// keeps the previous calendars on failure
+	Refresh() error
+
+	// Start reloading the calendars in the background
+	// so changes made on other replicas show up here
+	Start()
+
+	// Stop reloading the calendars
+	Stop()
+}
+
+// In memory copy of the exclusion calendars,
+// read by the recurrence engine without touching the database
+type store struct {
+	db           database.Database
+	mu           sync.RWMutex
+	calendars    map[string]map[string]bool
+	workingHours map[string]recurrence.WorkingHours
+	refresh      time.Duration
+	quit         chan struct{}
+	wg           sync.WaitGroup
+}
+
+const dateLayout = "2006-01-02"
+
+var (
+	storeInstance *store
+	log           = logger.GetLogger()
+)
+
+func NewStore(db database.Database) *store {
+	if storeInstance != nil {
+		return storeInstance
+	}
+
+	storeInstance = &store{
+		db:           db,
+		calendars:    map[string]map[string]bool{},
+		workingHours: map[string]recurrence.WorkingHours{"": workingHoursFromEnv()},
+		refresh:      getRefresh(),
+	}
+
+	if err := storeInstance.Refresh(); err != nil {
+		log.Info("Failed to load calendars", "message: ", err)
+	}
+
+	return storeInstance
+}
+
+func (s *store) Start() {
+	if s.quit != nil {
+		return
+	}
+
+	s.quit = make(chan struct{})
+	s.wg.Add(1)
+	go s.run()
+}
+
+func (s *store) Stop() {
+	if s.quit == nil {
+		return
+	}
+
+	close(s.quit)
+	s.wg.Wait()
+	s.quit = nil
+}
+
+func (s *store) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.quit:
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.Refresh(); err != nil {
+			log.Info("Failed to refresh calendars", "message: ", err)
+		}
+	}
+}
+
+func (s *store) Has(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.calendars[name]
+	return ok
+}
+
+func (s *store) Excluded(name string, date time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.calendars[name][date.Format(dateLayout)]
+}
+
+func (s *store) ExcludedBetween(name string, from time.Time, to time.Time) []time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Dates in the layout sort like the dates themselves
+	fromDate, toDate := from.Format(dateLayout), to.Format(dateLayout)
+
+	var dates []time.Time
+	for date := range s.calendars[name] {
+		if date < fromDate || date >= toDate {
+			continue
+		}
+		if parsed, err := time.Parse(dateLayout, date); err == nil {
+			dates = append(dates, parsed)
+		}
+	}
+
+	return dates
+}
+
+func (s *store) WorkingHours(name string) (recurrence.WorkingHours, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if workingHours, ok := s.workingHours[name]; ok {
+		return workingHours, true
+	}
+
+	workingHours, ok := s.workingHours[""]
+	return workingHours, ok
+}
+
+func (s *store) Refresh() error {
+	var calendars []models.Calendar
+	if result := s.db.Gorm().Find(&calendars); result.Error != nil {
+		return result.Error
+	}
+
+	var dates []models.CalendarDate
+	if result := s.db.Gorm().Find(&dates); result.Error != nil {
+		return result.Error
+	}
+
+	nameById := map[string]string{}
+	loaded := map[string]map[string]bool{}
+	workingHours := map[string]recurrence.WorkingHours{"": s.workingHours[""]}
+	for _, calendar := range calendars {
+		nameById[calendar.CalendarId.String()] = calendar.Name
+		loaded[calendar.Name] = map[string]bool{}
+
+		// Calendars without a working week use the default one
+		if calendar.WorkingDays == "" && calendar.WorkStart == "" && calendar.WorkEnd == "" {
+			continue
+		}
+
+		calendarHours, err := recurrence.ParseWorkingHours(calendar.WorkingDays, calendar.WorkStart, calendar.WorkEnd)
+		if err != nil {
+			log.Info("Invalid calendar working hours, using default", "calendar", calendar.Name, "message: ", err)
+			continue
+		}
+		workingHours[calendar.Name] = calendarHours
+	}
+
+	// Dates are stored without a zone and read back as UTC midnight
+	for _, date := range dates {
+		if name, ok := nameById[date.CalendarId.String()]; ok {
+			loaded[name][date.Date.UTC().Format(dateLayout)] = true
+		}
+	}
+
+	s.mu.Lock()
+	s.calendars = loaded
+	s.workingHours = workingHours
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Default working week from WORKING_DAYS (e.g. mon,tue,wed,thu,fri)
+// and WORKING_HOURS (e.g. 09:00-17:00)
+func workingHoursFromEnv() recurrence.WorkingHours {
+	days := fmt.Sprintf("%v", utils.GetEnvOrDefault("WORKING_DAYS", "mon,tue,wed,thu,fri"))
+	hours := fmt.Sprintf("%v", utils.GetEnvOrDefault("WORKING_HOURS", "09:00-17:00"))
+	start, end, _ := strings.Cut(hours, "-")
+
+	workingHours, err := recurrence.ParseWorkingHours(days, strings.TrimSpace(start), strings.TrimSpace(end))
+	if err != nil {
+		log.Info("Invalid WORKING_DAYS or WORKING_HOURS, using default", "message: ", err)
+		workingHours, _ = recurrence.ParseWorkingHours("", "", "")
+	}
+
+	return workingHours
+}
+
+// How often calendars are reloaded, CALENDAR_REFRESH
+func getRefresh() time.Duration {
+	refreshEnv := fmt.Sprintf("%v", utils.GetEnvOrDefault("CALENDAR_REFRESH", "1m"))
+
+	refresh, err := time.ParseDuration(refreshEnv)
+	if err != nil || refresh <= 0 {
+		log.Info("Invalid CALENDAR_REFRESH, using default 1m", "value", refreshEnv)
+		return time.Minute
+	}
+
+	return refresh
+}
@@ -0,0 +1,139 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+// An excluded date read from a calendar file
+type Date struct {
+	Date time.Time
+	Name string
+}
+
+// Years of recurring events expanded on import
+const expandYears = 5
+
+// ParseICS reads the event dates of an iCalendar (.ics) file.
+// Events spanning several days exclude every day they cover,
+// recurring events are expanded for the coming years.
+func ParseICS(reader io.Reader) ([]Date, error) {
+	lines, err := unfold(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var dates []Date
+	var event map[string]string
+	for _, line := range lines {
+		switch {
+		case line == "BEGIN:VEVENT":
+			event = map[string]string{}
+		case line == "END:VEVENT":
+			if event == nil {
+				continue
+			}
+
+			eventDates, err := eventDates(event)
+			if err != nil {
+				return nil, err
+			}
+			dates = append(dates, eventDates...)
+			event = nil
+		case event != nil:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				continue
+			}
+
+			// Parameters like ;VALUE=DATE or ;TZID= do not matter for dates
+			name, _, _ = strings.Cut(name, ";")
+			event[strings.ToUpper(name)] = value
+		}
+	}
+
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("no events found in calendar")
+	}
+
+	return dates, nil
+}
+
+// Join folded content lines, continuation lines start with a space or tab
+func unfold(reader io.Reader) ([]string, error) {
+	var lines []string
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+func eventDates(event map[string]string) ([]Date, error) {
+	start, err := parseDate(event["DTSTART"])
+	if err != nil {
+		return nil, err
+	}
+
+	// All day events end on the day after, exclusive
+	days := 1
+	if end, err := parseDate(event["DTEND"]); err == nil && end.After(start) {
+		days = int(end.Sub(start).Hours()/24 + 0.5)
+	}
+
+	starts := []time.Time{start}
+	if event["RRULE"] != "" {
+		option, err := rrule.StrToROption(event["RRULE"])
+		if err != nil {
+			return nil, fmt.Errorf("invalid event rule %q: %v", event["RRULE"], err)
+		}
+		option.Dtstart = start
+
+		rule, err := rrule.NewRRule(*option)
+		if err != nil {
+			return nil, fmt.Errorf("invalid event rule %q: %v", event["RRULE"], err)
+		}
+		starts = rule.Between(start, time.Now().AddDate(expandYears, 0, 0), true)
+	}
+
+	name := unescape(event["SUMMARY"])
+	var dates []Date
+	for _, start := range starts {
+		for day := 0; day < days; day++ {
+			dates = append(dates, Date{Date: start.AddDate(0, 0, day), Name: name})
+		}
+	}
+
+	return dates, nil
+}
+
+// Dates like 20250101 or date-times like 20250101T000000Z,
+// only the calendar date is kept
+func parseDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid event date %q", value)
+	}
+
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid event date %q", value)
+	}
+
+	return date, nil
+}
+
+func unescape(text string) string {
+	return strings.NewReplacer(`\,`, ",", `\;`, ";", `\n`, " ", `\N`, " ", `\\`, `\`).Replace(text)
+}
@@ -0,0 +1,81 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseICS(t *testing.T) {
+	// Recurring events are only expanded from now on
+	year := time.Now().Year() + 1
+
+	tests := []struct {
+		name    string
+		ics     string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "all day event",
+			ics: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART;VALUE=DATE:20250101\r\nDTEND;VALUE=DATE:20250102\r\n" +
+				"SUMMARY:New Year\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+			want: []string{"2025-01-01 New Year"},
+		},
+		{
+			name: "multi day event",
+			ics: "BEGIN:VEVENT\nDTSTART;VALUE=DATE:20251224\nDTEND;VALUE=DATE:20251227\n" +
+				"SUMMARY:Christmas\\, Boxing Day\nEND:VEVENT\n",
+			want: []string{"2025-12-24 Christmas, Boxing Day", "2025-12-25 Christmas, Boxing Day", "2025-12-26 Christmas, Boxing Day"},
+		},
+		{
+			name: "date time and folded summary",
+			ics:  "BEGIN:VEVENT\nDTSTART;TZID=Europe/Berlin:20250501T000000\nSUMMARY:Labour\n  Day\nEND:VEVENT\n",
+			want: []string{"2025-05-01 Labour Day"},
+		},
+		{
+			name: "recurring event",
+			ics:  fmt.Sprintf("BEGIN:VEVENT\nDTSTART;VALUE=DATE:%d0704\nRRULE:FREQ=YEARLY;COUNT=2\nSUMMARY:Independence Day\nEND:VEVENT\n", year),
+			want: []string{fmt.Sprintf("%d-07-04 Independence Day", year), fmt.Sprintf("%d-07-04 Independence Day", year+1)},
+		},
+		{
+			name:    "no events",
+			ics:     "BEGIN:VCALENDAR\nEND:VCALENDAR\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid date",
+			ics:     "BEGIN:VEVENT\nDTSTART:2025\nEND:VEVENT\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid rule",
+			ics:     "BEGIN:VEVENT\nDTSTART:20250101\nRRULE:FREQ=SOMETIMES\nEND:VEVENT\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dates, err := ParseICS(strings.NewReader(test.ics))
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ParseICS() = %v, want error", dates)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseICS(): %v", err)
+			}
+
+			var got []string
+			for _, date := range dates {
+				got = append(got, date.Date.Format("2006-01-02")+" "+date.Name)
+			}
+			if strings.Join(got, "|") != strings.Join(test.want, "|") {
+				t.Errorf("ParseICS() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
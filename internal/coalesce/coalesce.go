@@ -0,0 +1,108 @@
+package coalesce
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/message"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/notifier"
+	"github.com/kevinhartarto/tasker/internal/utils"
+)
+
+// Longest time reminders may be held back
+const maxWindow = 24 * time.Hour
+
+// A parsed coalescing window
+type Window struct {
+	Channel   string
+	Recipient string
+	Duration  time.Duration
+	Template  string
+}
+
+var log = logger.GetLogger()
+
+// Parse validates a coalescing window record and turns it into a window
+func Parse(coalesceWindow models.CoalesceWindow) (Window, error) {
+	window := Window{
+		Channel:   coalesceWindow.Channel,
+		Recipient: coalesceWindow.Recipient,
+		Template:  coalesceWindow.Template,
+	}
+
+	if window.Channel != "" {
+		channels := notifier.ParseChannels(window.Channel)
+		if len(channels) != 1 || !notifier.ValidChannels(channels[0]) {
+			return window, fmt.Errorf("unknown channel %q", window.Channel)
+		}
+		window.Channel = channels[0]
+	}
+
+	var err error
+	if window.Duration, err = time.ParseDuration(coalesceWindow.Window); err != nil {
+		return window, fmt.Errorf("window must be a duration like 5m")
+	}
+	if window.Duration < 0 || window.Duration > maxWindow {
+		return window, fmt.Errorf("window must be between 0s and %v", maxWindow)
+	}
+
+	if err := message.ValidateDigest(window.Template); err != nil {
+		return window, err
+	}
+
+	return window, nil
+}
+
+// Window for every channel and recipient from COALESCE_WINDOW (e.g. 2m),
+// with the digest template COALESCE_TEMPLATE
+func FromEnv() []Window {
+	duration := fmt.Sprintf("%v", utils.GetEnvOrDefault("COALESCE_WINDOW", ""))
+	if duration == "" {
+		return nil
+	}
+
+	window, err := Parse(models.CoalesceWindow{
+		Window:   duration,
+		Template: fmt.Sprintf("%v", utils.GetEnvOrDefault("COALESCE_TEMPLATE", "")),
+	})
+	if err != nil {
+		log.Info("Invalid COALESCE_WINDOW, ignoring", "message: ", err)
+		return nil
+	}
+
+	return []Window{window}
+}
+
+// Match returns the window for a channel and recipient.
+// A recipient window wins over a channel window, which wins over a window
+// for any, later windows win over earlier ones of the same kind.
+// False when no window matches.
+func Match(windows []Window, channel string, recipient string) (Window, bool) {
+	var matched Window
+	best := -1
+
+	for _, window := range windows {
+		if window.Channel != "" && window.Channel != channel {
+			continue
+		}
+		if window.Recipient != "" && window.Recipient != recipient {
+			continue
+		}
+
+		rank := 0
+		if window.Recipient != "" {
+			rank += 2
+		}
+		if window.Channel != "" {
+			rank += 1
+		}
+
+		if rank >= best {
+			matched, best = window, rank
+		}
+	}
+
+	return matched, best >= 0
+}
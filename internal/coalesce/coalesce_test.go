@@ -0,0 +1,85 @@
+package coalesce
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/notifier"
+)
+
+func TestParse(t *testing.T) {
+	t.Setenv("NOTIFIERS", "stdout")
+	notifier.Configured()
+
+	tests := []struct {
+		name    string
+		window  models.CoalesceWindow
+		want    Window
+		wantErr bool
+	}{
+		{"any channel", models.CoalesceWindow{Window: "5m"}, Window{Duration: 5 * time.Minute}, false},
+		{"channel", models.CoalesceWindow{Channel: " STDOUT ", Recipient: "ann", Window: "0s"},
+			Window{Channel: "stdout", Recipient: "ann"}, false},
+		{"unconfigured channel", models.CoalesceWindow{Channel: "kafka", Window: "5m"}, Window{}, true},
+		{"several channels", models.CoalesceWindow{Channel: "stdout,kafka", Window: "5m"}, Window{}, true},
+		{"invalid duration", models.CoalesceWindow{Window: "5"}, Window{}, true},
+		{"too long", models.CoalesceWindow{Window: "25h"}, Window{}, true},
+		{"negative", models.CoalesceWindow{Window: "-1m"}, Window{}, true},
+		{"invalid template", models.CoalesceWindow{Window: "5m", Template: "{{.Owner}}"}, Window{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.window)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Parse() = %+v, want error", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Parse(): %v", err)
+			}
+			if got != test.want {
+				t.Errorf("Parse() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	anything := Window{Duration: time.Minute}
+	webhook := Window{Channel: "webhook", Duration: 2 * time.Minute}
+	ann := Window{Recipient: "ann", Duration: 3 * time.Minute}
+	annWebhook := Window{Channel: "webhook", Recipient: "ann", Duration: 4 * time.Minute}
+	laterWebhook := Window{Channel: "webhook", Duration: 5 * time.Minute}
+
+	tests := []struct {
+		name      string
+		windows   []Window
+		channel   string
+		recipient string
+		want      Window
+		wantOk    bool
+	}{
+		{"no windows", nil, "webhook", "ann", Window{}, false},
+		{"other channel", []Window{webhook}, "stdout", "ann", Window{}, false},
+		{"any", []Window{anything}, "stdout", "bob", anything, true},
+		{"channel over any", []Window{webhook, anything}, "webhook", "bob", webhook, true},
+		{"recipient over channel", []Window{ann, webhook}, "webhook", "ann", ann, true},
+		{"recipient and channel over recipient", []Window{annWebhook, ann, webhook}, "webhook", "ann", annWebhook, true},
+		{"recipient on another channel", []Window{annWebhook, anything}, "stdout", "ann", anything, true},
+		{"later wins", []Window{webhook, laterWebhook}, "webhook", "bob", laterWebhook, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := Match(test.windows, test.channel, test.recipient)
+			if got != test.want || ok != test.wantOk {
+				t.Errorf("Match() = %+v, %v, want %+v, %v", got, ok, test.want, test.wantOk)
+			}
+		})
+	}
+}
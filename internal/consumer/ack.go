@@ -0,0 +1,194 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/kafkaconfig"
+	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"github.com/kevinhartarto/tasker/pkg/events"
+	"github.com/segmentio/kafka-go"
+)
+
+type Consumer interface {
+
+	// Start consuming in the background
+	// does nothing when the consumer is disabled
+	Start()
+
+	// Stop consuming
+	// waits for the message in progress to be handled
+	Stop()
+}
+
+// Anything able to apply acknowledgements,
+// implemented by the reminder controller
+type ackHandler interface {
+	HandleAck(events.ReminderAck) error
+}
+
+type ackConsumer struct {
+	handler    ackHandler
+	enabled    bool
+	backoffMin time.Duration
+	backoffMax time.Duration
+	reader     *kafka.Reader
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+var (
+	ackConsumerInstance *ackConsumer
+	log                 = logger.GetLogger()
+)
+
+// Consumer of reminder acknowledgements, enabled with KAFKA_ACK_ENABLED=1
+// and reading KAFKA_ACK_TOPIC as the KAFKA_ACK_GROUP consumer group.
+// Failures back off from KAFKA_ACK_BACKOFF_MIN_MS up to KAFKA_ACK_BACKOFF_MAX_MS.
+func NewAckConsumer(handler ackHandler) *ackConsumer {
+	if ackConsumerInstance != nil {
+		return ackConsumerInstance
+	}
+
+	ackConsumerInstance = &ackConsumer{
+		handler:    handler,
+		enabled:    kafkaconfig.GetEnv("KAFKA_ACK_ENABLED", "0") == "1",
+		backoffMin: time.Duration(utils.GetEnvIntOrDefault("KAFKA_ACK_BACKOFF_MIN_MS", 100)) * time.Millisecond,
+		backoffMax: time.Duration(utils.GetEnvIntOrDefault("KAFKA_ACK_BACKOFF_MAX_MS", 30000)) * time.Millisecond,
+	}
+
+	return ackConsumerInstance
+}
+
+func (ac *ackConsumer) Start() {
+	if !ac.enabled || ac.reader != nil {
+		return
+	}
+
+	dialer, err := kafkaconfig.Dialer()
+	if err != nil {
+		log.Error("Invalid kafka configuration, not consuming acknowledgements", "message: ", err)
+		return
+	}
+
+	topic := kafkaconfig.GetEnv("KAFKA_ACK_TOPIC", "tasker_reminder_ack")
+	ac.reader = kafka.NewReader(kafka.ReaderConfig{
+		Brokers:        kafkaconfig.Brokers(),
+		Topic:          topic,
+		GroupID:        kafkaconfig.GetEnv("KAFKA_ACK_GROUP", "tasker"),
+		Dialer:         dialer,
+		MinBytes:       1,
+		MaxBytes:       10e6,
+		MaxWait:        time.Second,
+		CommitInterval: time.Second,
+		StartOffset:    kafka.FirstOffset,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ac.cancel = cancel
+	ac.wg.Add(1)
+	go ac.run(ctx)
+
+	log.Info("Acknowledgement consumer started", "topic", topic)
+}
+
+func (ac *ackConsumer) Stop() {
+	if ac.reader == nil {
+		return
+	}
+
+	ac.cancel()
+	ac.wg.Wait()
+
+	if err := ac.reader.Close(); err != nil {
+		log.Info("Failed to close acknowledgement consumer", "message: ", err)
+	}
+	ac.reader = nil
+
+	log.Info("Acknowledgement consumer stopped")
+}
+
+func (ac *ackConsumer) run(ctx context.Context) {
+	defer ac.wg.Done()
+
+	retries := utils.GetEnvIntOrDefault("KAFKA_ACK_RETRIES", 3)
+	failures := 0
+	for {
+		message, err := ac.reader.FetchMessage(ctx)
+		if errors.Is(err, context.Canceled) {
+			return
+		} else if err != nil {
+			// The broker may be down, do not hammer it
+			log.Info("Failed to fetch acknowledgement", "message: ", err)
+			if !ac.backoff(ctx, failures) {
+				return
+			}
+			failures++
+			continue
+		}
+		failures = 0
+
+		if !ac.handle(ctx, message, retries) {
+			return
+		}
+
+		if err := ac.reader.CommitMessages(ctx, message); err != nil && !errors.Is(err, context.Canceled) {
+			log.Info("Failed to commit acknowledgement", "message: ", err)
+		}
+	}
+}
+
+// Invalid messages are skipped, failures are retried a few times
+// before the message is given up so one bad message cannot block the topic
+// return false when the consumer stopped before the message was handled
+func (ac *ackConsumer) handle(ctx context.Context, message kafka.Message, retries int) bool {
+	var ack events.ReminderAck
+	if err := json.Unmarshal(message.Value, &ack); err != nil {
+		log.Info("Invalid acknowledgement, skipping", "offset", message.Offset, "message: ", err)
+		return true
+	}
+
+	if ack.SchemaVersion > events.AckSchemaVersion {
+		log.Info("Unsupported acknowledgement schema, skipping", "offset", message.Offset, "schema_version", ack.SchemaVersion)
+		return true
+	}
+
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 && !ac.backoff(ctx, attempt-1) {
+			return false
+		}
+
+		if err = ac.handler.HandleAck(ack); err == nil {
+			return true
+		}
+	}
+
+	log.Info("Failed to handle acknowledgement, skipping", "offset", message.Offset, "event", ack.EventId, "message: ", err)
+	return true
+}
+
+// Wait after the given number of failures in a row,
+// doubling from backoffMin up to backoffMax
+// return false when the consumer stopped in the meantime
+func (ac *ackConsumer) backoff(ctx context.Context, failures int) bool {
+	delay := ac.backoffMin
+	for i := 0; i < failures && delay < ac.backoffMax; i++ {
+		delay *= 2
+	}
+	delay = min(delay, ac.backoffMax)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
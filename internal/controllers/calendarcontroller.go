@@ -0,0 +1,414 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/calendar"
+	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/delayqueue"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/recurrence"
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CalendarController interface {
+
+	// Create a calendar with its excluded dates and working hours
+	// return calendar name and uuid
+	CreateCalendar(*fiber.Ctx) error
+
+	// Query all calendars
+	// return an array of calendars
+	GetAllCalendars(*fiber.Ctx) error
+
+	// Query a calendar by name
+	// return the calendar with its excluded dates
+	GetCalendar(string, *fiber.Ctx) error
+
+	// Add excluded dates to a calendar
+	// return the number of dates added
+	AddCalendarDates(string, *fiber.Ctx) error
+
+	// Import the events of an .ics file into a calendar,
+	// the calendar is created when it does not exist
+	// return the number of dates added
+	ImportCalendar(string, *fiber.Ctx) error
+
+	// Delete a calendar not used by any reminder
+	DeleteCalendar(string, *fiber.Ctx) error
+}
+
+type calendarController struct {
+	db        database.Database
+	queue     delayqueue.Queue
+	calendars calendar.Store
+}
+
+// An excluded date as entered through the API
+type calendarDate struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+type calendarRequest struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	WorkingDays string         `json:"working_days"`
+	WorkStart   string         `json:"work_start"`
+	WorkEnd     string         `json:"work_end"`
+	Dates       []calendarDate `json:"dates"`
+}
+
+var (
+	calendarInstance *calendarController
+
+	errCalendarNotFound = errors.New("calendar not found")
+	errCalendarInUse    = errors.New("calendar in use")
+)
+
+func InitCalendarController(db database.Database, queue delayqueue.Queue) *calendarController {
+	if calendarInstance != nil {
+		return calendarInstance
+	}
+
+	calendarInstance = &calendarController{
+		db:        db,
+		queue:     queue,
+		calendars: calendar.NewStore(db),
+	}
+
+	return calendarInstance
+}
+
+func (cc *calendarController) CreateCalendar(c *fiber.Ctx) error {
+	var request calendarRequest
+
+	if err := c.BodyParser(&request); err != nil || request.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid JSON input",
+		})
+	}
+
+	dates, err := parseCalendarDates(request.Dates)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Working hours are optional, the default working week applies without them
+	if request.WorkingDays != "" || request.WorkStart != "" || request.WorkEnd != "" {
+		if _, err := recurrence.ParseWorkingHours(request.WorkingDays, request.WorkStart, request.WorkEnd); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	newCalendar := models.Calendar{
+		CalendarId:  utils.GenerateNewUUID(),
+		Name:        request.Name,
+		Description: request.Description,
+		WorkingDays: request.WorkingDays,
+		WorkStart:   request.WorkStart,
+		WorkEnd:     request.WorkEnd,
+	}
+
+	err = cc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&newCalendar).Error; err != nil {
+			return err
+		}
+
+		_, err := addCalendarDates(tx, newCalendar, dates)
+		return err
+	})
+
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("Calendar %s already exists", request.Name),
+		})
+	} else if err != nil {
+		return err
+	}
+
+	cc.refresh()
+
+	message := fmt.Sprintf("Calendar %s (%v) created", newCalendar.Name, newCalendar.CalendarId)
+	return c.Status(fiber.StatusCreated).SendString(message)
+}
+
+func (cc *calendarController) GetAllCalendars(c *fiber.Ctx) error {
+	var calendars []models.Calendar
+	result := cc.db.Gorm().Order("name").Find(&calendars)
+
+	if result.Error != nil {
+		return result.Error
+	} else if len(calendars) == 0 {
+		return c.Status(fiber.StatusOK).SendString("Calendars not found")
+	} else {
+		return c.Status(fiber.StatusOK).JSON(calendars)
+	}
+}
+
+func (cc *calendarController) GetCalendar(name string, c *fiber.Ctx) error {
+	var existing models.Calendar
+	if result := cc.db.Gorm().Where("name = ?", name).Limit(1).Find(&existing); result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Calendar not found",
+		})
+	}
+
+	var dates []models.CalendarDate
+	if result := cc.db.Gorm().Where("calendar_id = ?", existing.CalendarId).Order("date").Find(&dates); result.Error != nil {
+		return result.Error
+	}
+
+	var response []calendarDate
+	for _, date := range dates {
+		response = append(response, calendarDate{
+			Date: date.Date.UTC().Format(time.DateOnly),
+			Name: date.Name,
+		})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"calendar_id":  existing.CalendarId,
+		"name":         existing.Name,
+		"description":  existing.Description,
+		"working_days": existing.WorkingDays,
+		"work_start":   existing.WorkStart,
+		"work_end":     existing.WorkEnd,
+		"dates":        response,
+		"updated_at":   existing.UpdatedAt,
+	})
+}
+
+func (cc *calendarController) AddCalendarDates(name string, c *fiber.Ctx) error {
+	var request []calendarDate
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid JSON input",
+		})
+	}
+
+	dates, err := parseCalendarDates(request)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return cc.saveDates(name, dates, false, c)
+}
+
+func (cc *calendarController) ImportCalendar(name string, c *fiber.Ctx) error {
+	dates, err := calendar.ParseICS(bytes.NewReader(c.Body()))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return cc.saveDates(name, dates, true, c)
+}
+
+func (cc *calendarController) DeleteCalendar(name string, c *fiber.Ctx) error {
+	var used, deleted int64
+
+	err := cc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		// Reminders take a share lock on the calendar they use,
+		// none can start using it until the delete commits
+		var existing models.Calendar
+		if result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("name = ?", name).Limit(1).Find(&existing); result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			return nil
+		}
+
+		if result := tx.Model(&models.Reminder{}).Where("calendar = ?", name).Count(&used); result.Error != nil {
+			return result.Error
+		} else if used > 0 {
+			return errCalendarInUse
+		}
+
+		if err := tx.Where("calendar_id = ?", existing.CalendarId).Delete(&models.CalendarDate{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Delete(&existing)
+		deleted = result.RowsAffected
+		return result.Error
+	})
+
+	if errors.Is(err, errCalendarInUse) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("Calendar is used by %d reminders", used),
+		})
+	}
+
+	if err != nil {
+		return err
+	} else if deleted == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Calendar not found",
+		})
+	}
+
+	cc.refresh()
+
+	message := fmt.Sprintf("Calendar %s deleted", name)
+	return c.Status(fiber.StatusOK).SendString(message)
+}
+
+// Add dates to a calendar and reschedule the reminders using it
+func (cc *calendarController) saveDates(name string, dates []calendar.Date, create bool, c *fiber.Ctx) error {
+	var added int64
+
+	err := cc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		var existing models.Calendar
+		if result := tx.Where("name = ?", name).Limit(1).Find(&existing); result.Error != nil {
+			return result.Error
+		} else if result.RowsAffected == 0 {
+			if !create {
+				return errCalendarNotFound
+			}
+
+			existing = models.Calendar{
+				CalendarId: utils.GenerateNewUUID(),
+				Name:       name,
+			}
+			if err := tx.Create(&existing).Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		added, err = addCalendarDates(tx, existing, dates)
+		return err
+	})
+
+	if errors.Is(err, errCalendarNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Calendar not found",
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	cc.refresh()
+
+	rescheduledIds, err := rescheduleCalendar(cc.db.Gorm(), name)
+	if err != nil {
+		log.Info("Failed to reschedule calendar reminders", "calendar", name, "message: ", err)
+	}
+	syncDelayQueue(cc.db, cc.queue, rescheduledIds)
+
+	message := fmt.Sprintf("%d dates added to calendar %s", added, name)
+	return c.Status(fiber.StatusOK).SendString(message)
+}
+
+func (cc *calendarController) refresh() {
+	if err := cc.calendars.Refresh(); err != nil {
+		log.Info("Failed to refresh calendars", "message: ", err)
+	}
+}
+
+// Dates already in the calendar are kept as they are
+func addCalendarDates(tx *gorm.DB, existing models.Calendar, dates []calendar.Date) (int64, error) {
+	if len(dates) == 0 {
+		return 0, nil
+	}
+
+	var records []models.CalendarDate
+	for _, date := range dates {
+		records = append(records, models.CalendarDate{
+			CalendarDateId: utils.GenerateNewUUID(),
+			CalendarId:     existing.CalendarId,
+			Date:           date.Date,
+			Name:           date.Name,
+		})
+	}
+
+	result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&records)
+	return result.RowsAffected, result.Error
+}
+
+func parseCalendarDates(request []calendarDate) ([]calendar.Date, error) {
+	var dates []calendar.Date
+
+	for _, date := range request {
+		parsed, err := time.Parse(time.DateOnly, date.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date.Date)
+		}
+		dates = append(dates, calendar.Date{Date: parsed, Name: date.Name})
+	}
+
+	return dates, nil
+}
+
+// Move the next occurrence of reminders using a calendar
+// off dates newly excluded by it, following their calendar policy.
+// An occurrence moved back before now gives way to the one after.
+// return the reminders moved
+func rescheduleCalendar(tx *gorm.DB, name string) ([]uuid.UUID, error) {
+	var reminders []models.Reminder
+	result := tx.Where("calendar = ? AND next_reminder IS NOT NULL", name).Find(&reminders)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	now := time.Now()
+	var rescheduledIds []uuid.UUID
+	for _, reminder := range reminders {
+		if !recurrence.Excluded(reminder, *reminder.NextReminder) {
+			continue
+		}
+
+		nextReminder := recurrence.Next(reminder, reminder.NextReminder.Add(-time.Nanosecond))
+		if adjusted, ok := recurrence.Adjusted(reminder, *reminder.NextReminder); ok && adjusted.After(now) &&
+			(nextReminder == nil || adjusted.Before(*nextReminder)) {
+			nextReminder = &adjusted
+		}
+
+		if err := tx.Model(&models.Reminder{}).
+			Where("reminder_id = ?", reminder.ReminderId).
+			Update("next_reminder", nextReminder).Error; err != nil {
+			return rescheduledIds, err
+		}
+		rescheduledIds = append(rescheduledIds, reminder.ReminderId)
+	}
+
+	return rescheduledIds, nil
+}
+
+// Hold a share lock on the calendar of a reminder while it is saved,
+// so the calendar cannot be deleted before the reminder commits
+func lockReminderCalendar(tx *gorm.DB, name string) error {
+	if name == "" {
+		return nil
+	}
+
+	var calendars []models.Calendar
+	result := tx.Clauses(clause.Locking{Strength: "SHARE"}).Where("name = ?", name).Limit(1).Find(&calendars)
+	if result.Error != nil {
+		return result.Error
+	}
+	if len(calendars) == 0 {
+		return errInvalidReminder
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/coalesce"
+	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"gorm.io/gorm"
+)
+
+type CoalesceWindowController interface {
+
+	// Create a coalescing window for a channel and recipient
+	// return coalescing window uuid
+	CreateCoalesceWindow(*fiber.Ctx) error
+
+	// Query all coalescing windows
+	// return an array of coalescing windows
+	GetAllCoalesceWindows(*fiber.Ctx) error
+
+	// Delete a coalescing window by uuid
+	DeleteCoalesceWindow(uuid.UUID, *fiber.Ctx) error
+}
+
+type coalesceWindowController struct {
+	db database.Database
+}
+
+var coalesceWindowInstance *coalesceWindowController
+
+func InitCoalesceWindowController(db database.Database) *coalesceWindowController {
+	if coalesceWindowInstance != nil {
+		return coalesceWindowInstance
+	}
+
+	coalesceWindowInstance = &coalesceWindowController{
+		db: db,
+	}
+
+	return coalesceWindowInstance
+}
+
+func (wc *coalesceWindowController) CreateCoalesceWindow(c *fiber.Ctx) error {
+	var newWindow models.CoalesceWindow
+
+	if err := c.BodyParser(&newWindow); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid JSON input",
+		})
+	}
+
+	newWindow.CoalesceWindowId = utils.GenerateNewUUID()
+
+	window, err := coalesce.Parse(newWindow)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	newWindow.Channel = window.Channel
+	newWindow.Window = window.Duration.String()
+
+	result := wc.db.Gorm().Create(&newWindow)
+
+	if errors.Is(result.Error, gorm.ErrDuplicatedKey) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Coalescing window for this channel and recipient already exists",
+		})
+	} else if result.Error != nil {
+		return result.Error
+	} else {
+		message := fmt.Sprintf("Coalescing window %s (%v) created", newWindow.Window, newWindow.CoalesceWindowId)
+		return c.Status(fiber.StatusCreated).SendString(message)
+	}
+}
+
+func (wc *coalesceWindowController) GetAllCoalesceWindows(c *fiber.Ctx) error {
+	var coalesceWindows []models.CoalesceWindow
+	result := wc.db.Gorm().Order("created_at").Find(&coalesceWindows)
+
+	if result.Error != nil {
+		return result.Error
+	} else {
+		var response []fiber.Map
+		for _, window := range coalesceWindows {
+			response = append(response, fiber.Map{
+				"coalesce_window_id": window.CoalesceWindowId,
+				"channel":            window.Channel,
+				"recipient":          window.Recipient,
+				"window":             window.Window,
+				"template":           window.Template,
+				"updated_at":         window.UpdatedAt,
+			})
+		}
+
+		if response == nil {
+			return c.Status(fiber.StatusOK).SendString("Coalescing windows not found")
+		}
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+func (wc *coalesceWindowController) DeleteCoalesceWindow(uuid uuid.UUID, c *fiber.Ctx) error {
+	result := wc.db.Gorm().Where("coalesce_window_id = ?", uuid).Delete(&models.CoalesceWindow{})
+
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Coalescing window not found",
+		})
+	} else {
+		message := fmt.Sprintf("Coalescing window (%v) deleted", uuid)
+		return c.Status(fiber.StatusOK).SendString(message)
+	}
+}
@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/outbox"
+	"gorm.io/gorm"
+)
+
+type DeadLetterController interface {
+
+	// Query dead letters, filtered by channel, reminder and age
+	// return an array of dead letters, latest first
+	GetDeadLetters(*fiber.Ctx) error
+
+	// Query a dead letter by uuid
+	// return the dead letter with its payload
+	GetDeadLetter(uuid.UUID, *fiber.Ctx) error
+
+	// Put a dead letter back into the outbox
+	RetryDeadLetter(uuid.UUID, *fiber.Ctx) error
+
+	// Put every dead letter matching the filters back into the outbox
+	// return the number of dead letters retried
+	RetryDeadLetters(*fiber.Ctx) error
+
+	// Delete a dead letter by uuid
+	DeleteDeadLetter(uuid.UUID, *fiber.Ctx) error
+
+	// Delete every dead letter matching the filters
+	// return the number of dead letters purged
+	PurgeDeadLetters(*fiber.Ctx) error
+}
+
+type deadLetterController struct {
+	db database.Database
+}
+
+var (
+	deadLetterInstance *deadLetterController
+
+	errInvalidFilter = errors.New("invalid filter")
+)
+
+func InitDeadLetterController(db database.Database) *deadLetterController {
+	if deadLetterInstance != nil {
+		return deadLetterInstance
+	}
+
+	deadLetterInstance = &deadLetterController{
+		db: db,
+	}
+
+	return deadLetterInstance
+}
+
+func (dc *deadLetterController) GetDeadLetters(c *fiber.Ctx) error {
+	query, err := deadLetterFilter(dc.db.Gorm(), c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	limit := c.QueryInt("limit", 100)
+	if limit <= 0 || limit > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit must be between 1 and 500",
+		})
+	}
+
+	var deadLetters []models.DeadLetter
+	result := query.Order("failed_at DESC").Limit(limit).Find(&deadLetters)
+
+	if result.Error != nil {
+		return result.Error
+	} else {
+		var response []fiber.Map
+		for _, deadLetter := range deadLetters {
+			response = append(response, fiber.Map{
+				"dead_letter_id": deadLetter.DeadLetterId,
+				"event_id":       deadLetter.EventId,
+				"reminder_id":    deadLetter.ReminderId,
+				"task_id":        deadLetter.TaskId,
+				"channel":        deadLetter.Channel,
+				"occurrence":     deadLetter.Occurrence,
+				"attempts":       deadLetter.Attempts,
+				"error":          deadLetter.Error,
+				"failed_at":      deadLetter.FailedAt,
+			})
+		}
+
+		if response == nil {
+			return c.Status(fiber.StatusOK).SendString("Dead letters not found")
+		}
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+func (dc *deadLetterController) GetDeadLetter(uuid uuid.UUID, c *fiber.Ctx) error {
+	var deadLetter models.DeadLetter
+	result := dc.db.Gorm().Where("dead_letter_id = ?", uuid).First(&deadLetter)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Dead letter not found",
+		})
+	} else if result.Error != nil {
+		return result.Error
+	} else {
+		return c.Status(fiber.StatusOK).JSON(deadLetter)
+	}
+}
+
+func (dc *deadLetterController) RetryDeadLetter(uuid uuid.UUID, c *fiber.Ctx) error {
+	var retried int
+
+	err := dc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		var deadLetter models.DeadLetter
+		if err := tx.Where("dead_letter_id = ?", uuid).First(&deadLetter).Error; err != nil {
+			return err
+		}
+
+		var err error
+		retried, err = outbox.Redeliver(tx, []models.DeadLetter{deadLetter})
+		return err
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Dead letter not found",
+		})
+	}
+
+	if err != nil {
+		return err
+	} else if retried == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Event is still queued for delivery on this channel",
+		})
+	} else {
+		message := fmt.Sprintf("Dead letter (%v) queued for delivery", uuid)
+		return c.Status(fiber.StatusOK).SendString(message)
+	}
+}
+
+func (dc *deadLetterController) RetryDeadLetters(c *fiber.Ctx) error {
+	var found, retried int
+
+	err := dc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		query, err := deadLetterFilter(tx, c)
+		if err != nil {
+			return err
+		}
+
+		var deadLetters []models.DeadLetter
+		if err := query.Find(&deadLetters).Error; err != nil {
+			return err
+		}
+		found = len(deadLetters)
+
+		retried, err = outbox.Redeliver(tx, deadLetters)
+		return err
+	})
+
+	if errors.Is(err, errInvalidFilter) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err != nil {
+		return err
+	} else {
+		// The others are still queued for delivery and kept
+		message := fmt.Sprintf("%d of %d dead letters queued for delivery", retried, found)
+		return c.Status(fiber.StatusOK).SendString(message)
+	}
+}
+
+func (dc *deadLetterController) DeleteDeadLetter(uuid uuid.UUID, c *fiber.Ctx) error {
+	result := dc.db.Gorm().Where("dead_letter_id = ?", uuid).Delete(&models.DeadLetter{})
+
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Dead letter not found",
+		})
+	} else {
+		message := fmt.Sprintf("Dead letter (%v) deleted", uuid)
+		return c.Status(fiber.StatusOK).SendString(message)
+	}
+}
+
+func (dc *deadLetterController) PurgeDeadLetters(c *fiber.Ctx) error {
+	query, err := deadLetterFilter(dc.db.Gorm(), c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Without filters everything goes, gorm needs a condition for that
+	result := query.Where("1 = 1").Delete(&models.DeadLetter{})
+
+	if result.Error != nil {
+		return result.Error
+	} else {
+		message := fmt.Sprintf("%d dead letters purged", result.RowsAffected)
+		return c.Status(fiber.StatusOK).SendString(message)
+	}
+}
+
+// Filter dead letters by the channel, reminder and older_than query parameters,
+// older_than is a duration like 72h
+func deadLetterFilter(tx *gorm.DB, c *fiber.Ctx) (*gorm.DB, error) {
+	query := tx.Model(&models.DeadLetter{})
+
+	if channel := c.Query("channel"); channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+
+	if reminder := c.Query("reminder"); reminder != "" {
+		reminderId, err := uuid.Parse(reminder)
+		if err != nil {
+			return nil, fmt.Errorf("%w: reminder must be a uuid", errInvalidFilter)
+		}
+		query = query.Where("reminder_id = ?", reminderId)
+	}
+
+	if olderThan := c.Query("older_than"); olderThan != "" {
+		age, err := time.ParseDuration(olderThan)
+		if err != nil || age < 0 {
+			return nil, fmt.Errorf("%w: older_than must be a duration like 72h", errInvalidFilter)
+		}
+		query = query.Where("failed_at <= ?", time.Now().Add(-age))
+	}
+
+	return query, nil
+}
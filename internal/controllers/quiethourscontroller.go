@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/quiethours"
+	"github.com/kevinhartarto/tasker/internal/utils"
+)
+
+type QuietHoursController interface {
+
+	// Create a quiet hours window
+	// return quiet hours scope and uuid
+	CreateQuietHours(*fiber.Ctx) error
+
+	// Query all quiet hours windows
+	// return an array of quiet hours windows
+	GetAllQuietHours(*fiber.Ctx) error
+
+	// Delete a quiet hours window by uuid
+	DeleteQuietHours(uuid.UUID, *fiber.Ctx) error
+}
+
+type quietHoursController struct {
+	db database.Database
+}
+
+var quietHoursInstance *quietHoursController
+
+func InitQuietHoursController(db database.Database) *quietHoursController {
+	if quietHoursInstance != nil {
+		return quietHoursInstance
+	}
+
+	quietHoursInstance = &quietHoursController{
+		db: db,
+	}
+
+	return quietHoursInstance
+}
+
+func (qc *quietHoursController) CreateQuietHours(c *fiber.Ctx) error {
+	var newQuietHours models.QuietHours
+
+	if err := c.BodyParser(&newQuietHours); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid JSON input",
+		})
+	}
+
+	newQuietHours.QuietHoursId = utils.GenerateNewUUID()
+	if newQuietHours.TimeZone == "" {
+		newQuietHours.TimeZone = "UTC"
+	}
+
+	window, err := quiethours.Parse(newQuietHours)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	newQuietHours.Policy = window.Policy
+
+	result := qc.db.Gorm().Create(&newQuietHours)
+
+	if result.Error != nil {
+		return result.Error
+	} else {
+		message := fmt.Sprintf("Quiet hours %s (%v) created", newQuietHours.Scope, newQuietHours.QuietHoursId)
+		return c.Status(fiber.StatusCreated).SendString(message)
+	}
+}
+
+func (qc *quietHoursController) GetAllQuietHours(c *fiber.Ctx) error {
+	var quietHours []models.QuietHours
+	result := qc.db.Gorm().Order("created_at").Find(&quietHours)
+
+	if result.Error != nil {
+		return result.Error
+	} else {
+		var response []fiber.Map
+		for _, window := range quietHours {
+			response = append(response, fiber.Map{
+				"quiet_hours_id": window.QuietHoursId,
+				"scope":          window.Scope,
+				"subject":        window.Subject,
+				"start_time":     window.StartTime,
+				"end_time":       window.EndTime,
+				"time_zone":      window.TimeZone,
+				"policy":         window.Policy,
+				"updated_at":     window.UpdatedAt,
+			})
+		}
+
+		if response == nil {
+			return c.Status(fiber.StatusOK).SendString("Quiet hours not found")
+		}
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+func (qc *quietHoursController) DeleteQuietHours(uuid uuid.UUID, c *fiber.Ctx) error {
+	result := qc.db.Gorm().Where("quiet_hours_id = ?", uuid).Delete(&models.QuietHours{})
+
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Quiet hours not found",
+		})
+	} else {
+		message := fmt.Sprintf("Quiet hours (%v) deleted", uuid)
+		return c.Status(fiber.StatusOK).SendString(message)
+	}
+}
+
+// Quiet hours windows of the environment and the database
+func loadQuietHours(db database.Database) []quiethours.Window {
+	windows := quiethours.FromEnv()
+
+	var quietHours []models.QuietHours
+	if result := db.Gorm().Find(&quietHours); result.Error != nil {
+		log.Info("Failed to query quiet hours", "message: ", result.Error)
+		return windows
+	}
+
+	for _, record := range quietHours {
+		window, err := quiethours.Parse(record)
+		if err != nil {
+			log.Info("Invalid quiet hours, ignoring", "quiet_hours", record.QuietHoursId, "message: ", err)
+			continue
+		}
+		windows = append(windows, window)
+	}
+
+	return windows
+}
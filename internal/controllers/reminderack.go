@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/outbox"
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"github.com/kevinhartarto/tasker/pkg/events"
+	"gorm.io/gorm"
+)
+
+// Delivery status recorded for every acknowledgement status
+var ackDeliveryStatus = map[string]string{
+	events.AckDelivered: models.DeliveryDelivered,
+	events.AckRead:      models.DeliveryRead,
+	events.AckSnoozed:   models.DeliverySnoozed,
+	events.AckFailed:    models.DeliveryFailed,
+}
+
+func (rc *reminderController) HandleAck(ack events.ReminderAck) error {
+	deliveryStatus, ok := ackDeliveryStatus[ack.Status]
+	if !ok {
+		log.Info("Unknown acknowledgement status, skipping", "event", ack.EventId, "status", ack.Status)
+		return nil
+	}
+
+	var occurrence models.ReminderOccurrence
+	result := rc.db.Gorm().Where("occurrence_id = ?", ack.OccurrenceId).First(&occurrence)
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		log.Info("Acknowledged occurrence not found, skipping", "event", ack.EventId, "occurrence", ack.OccurrenceId)
+		return nil
+	} else if result.Error != nil {
+		return result.Error
+	}
+
+	if ack.Status == events.AckSnoozed && (ack.SnoozedUntil == nil || !ack.SnoozedUntil.After(time.Now())) {
+		log.Info("Snooze acknowledgement without a future time, skipping", "event", ack.EventId)
+		return nil
+	}
+
+	reportedAt := ack.ReportedAt
+	if reportedAt.IsZero() {
+		reportedAt = time.Now()
+	}
+
+	return rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		var message models.Outbox
+		if err := tx.Where("event_id = ? AND channel = ?", ack.EventId, ack.Channel).Limit(1).Find(&message).Error; err != nil {
+			return err
+		}
+
+		delivery := models.ReminderDelivery{
+			DeliveryId:  utils.GenerateNewUUID(),
+			ReminderId:  occurrence.ReminderId,
+			OutboxId:    message.OutboxId,
+			EventId:     ack.EventId,
+			Occurrence:  occurrence.Occurrence,
+			Channel:     ack.Channel,
+			Status:      deliveryStatus,
+			Error:       ack.Error,
+			AttemptedAt: reportedAt,
+		}
+		if err := tx.Create(&delivery).Error; err != nil {
+			return err
+		}
+
+		switch ack.Status {
+		case events.AckRead:
+			return tx.Model(&models.ReminderOccurrence{}).
+				Where("occurrence_id = ? AND status IN ?", occurrence.OccurrenceId,
+					[]string{models.OccurrenceFired, models.OccurrenceSnoozed}).
+				Updates(map[string]interface{}{
+					"status":          models.OccurrenceAcknowledged,
+					"acknowledged_at": reportedAt,
+					"snoozed_until":   nil,
+				}).Error
+		case events.AckSnoozed:
+			return tx.Model(&models.ReminderOccurrence{}).
+				Where("occurrence_id = ? AND status = ?", occurrence.OccurrenceId, models.OccurrenceFired).
+				Updates(map[string]interface{}{
+					"status":        models.OccurrenceSnoozed,
+					"snoozed_until": *ack.SnoozedUntil,
+					"snooze_count":  gorm.Expr("snooze_count + 1"),
+				}).Error
+		case events.AckFailed:
+			return rc.resendOutbox(tx, message, ack.Error)
+		}
+
+		return nil
+	})
+}
+
+// Have the relay publish a message downstream failed to deliver again,
+// up to ACK_RESEND_LIMIT attempts before it becomes a dead letter
+func (rc *reminderController) resendOutbox(tx *gorm.DB, message models.Outbox, reason string) error {
+	if message.OutboxId == uuid.Nil || message.SentAt == nil {
+		return nil
+	}
+
+	if message.Attempts >= rc.ackResendLimit {
+		log.Info("Reminder failed downstream, giving up", "event", message.EventId, "channel", message.Channel, "attempts", message.Attempts)
+		return outbox.DeadLetter(tx, message, reason)
+	}
+
+	return tx.Model(&models.Outbox{}).
+		Where("outbox_id = ?", message.OutboxId).
+		Updates(map[string]interface{}{
+			"sent_at":         nil,
+			"last_error":      reason,
+			"next_attempt_at": nil,
+		}).Error
+}
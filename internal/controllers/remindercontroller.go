@@ -1,62 +1,155 @@
 package controllers
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/calendar"
 	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/delayqueue"
 	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/message"
 	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/notifier"
+	"github.com/kevinhartarto/tasker/internal/outbox"
+	"github.com/kevinhartarto/tasker/internal/recurrence"
 	"github.com/kevinhartarto/tasker/internal/utils"
-	"github.com/segmentio/kafka-go"
+	"github.com/kevinhartarto/tasker/pkg/events"
+	"gorm.io/gorm"
 )
 
 type ReminderController interface {
 
 	// Create a reminder for a task
 	// return reminder name and uuid
-	CreateRemainder(fiber.Ctx) error
+	CreateRemainder(*fiber.Ctx) error
 
 	// Query all reminders
 	// return an array of all reminders
-	GetAllReminders(fiber.Ctx) error
+	GetAllReminders(*fiber.Ctx) error
 
 	// Query a reminder by reminder UUID
 	// return a reminder details
-	GetReminderByUuid(uuid.UUID, fiber.Ctx) error
+	GetReminderByUuid(uuid.UUID, *fiber.Ctx) error
 
 	// Query a reminder by task UUID
 	// return a reminder details
-	GetReminderByTaskUuid(uuid.UUID, fiber.Ctx) error
+	GetReminderByTaskUuid(uuid.UUID, *fiber.Ctx) error
+
+	// Create a reminder for the task of the given UUID
+	// return reminder name and uuid
+	AddTaskReminder(uuid.UUID, *fiber.Ctx) error
+
+	// Query all reminders of a task by task UUID
+	// return an array of reminders
+	GetTaskReminders(uuid.UUID, *fiber.Ctx) error
 
 	// Update a reminder
-	UpdateRemainder(fiber.Ctx) error
+	UpdateRemainder(*fiber.Ctx) error
+
+	// Stop sending a reminder of a task until it is resumed
+	PauseReminder(uuid.UUID, uuid.UUID, *fiber.Ctx) error
+
+	// Send a paused reminder again from its next occurrence
+	ResumeReminder(uuid.UUID, uuid.UUID, *fiber.Ctx) error
+
+	// Delete a reminder of a task
+	DeleteReminder(uuid.UUID, uuid.UUID, *fiber.Ctx) error
+
+	// Expand the schedule of a reminder by reminder UUID
+	// return the upcoming occurrences between from and to
+	GetReminderOccurrences(uuid.UUID, *fiber.Ctx) error
+
+	// Expand the schedule of an unsaved reminder
+	// return the upcoming occurrences between from and to
+	PreviewReminderOccurrences(*fiber.Ctx) error
+
+	// Query delivery attempts of a reminder by reminder UUID
+	// return an array of deliveries, latest first
+	GetReminderDeliveries(uuid.UUID, *fiber.Ctx) error
+
+	// Snooze a fired occurrence by a duration or until a time
+	// the latest fired occurrence is used when none is given
+	SnoozeReminder(uuid.UUID, *fiber.Ctx) error
 
-	// Send kafka message to mailman
-	// this will be handled by a cron job
-	SendReminder()
+	// Acknowledge a fired occurrence
+	// the latest fired occurrence is used when none is given
+	AcknowledgeReminder(uuid.UUID, *fiber.Ctx) error
+
+	// Replace the escalation chain of a reminder by reminder UUID
+	// an empty chain removes the escalation
+	SetReminderEscalation(uuid.UUID, *fiber.Ctx) error
+
+	// Query the escalation chain of a reminder by reminder UUID
+	// return an array of escalation steps in order
+	GetReminderEscalation(uuid.UUID, *fiber.Ctx) error
+
+	// Apply an acknowledgement reported by a downstream service
+	// to the delivery state and the occurrence it is about
+	HandleAck(events.ReminderAck) error
+
+	// Send due reminders through their notification channels
+	// as the leader holding the given fencing token
+	SendReminder(int64)
+
+	// Send reminders the delay queue reports due
+	// as the leader holding the given fencing token
+	SendQueuedReminders(int64)
+
+	// Deal with occurrences missed while no replica was leading
+	// following the catch up policy of every reminder
+	CatchUpReminders(time.Duration, int64)
+
+	// Close notification channels and stop reloading calendars
+	Close()
 }
 
 type reminderController struct {
-	db database.Database
+	db             database.Database
+	notifiers      []notifier.Notifier
+	relay          outbox.Relay
+	queue          delayqueue.Queue
+	calendars      calendar.Store
+	catchUpLimit   int
+	ackResendLimit int
+	batchSize      int
+	maxBatches     int
 }
 
 var (
 	reminderInstance *reminderController
 	log              = logger.GetLogger()
+
+	errInvalidReminder = errors.New("invalid reminder")
+	errInvalidTemplate = errors.New("invalid template")
 )
 
-func InitReminderController(db database.Database) *reminderController {
+func InitReminderController(db database.Database, queue delayqueue.Queue) *reminderController {
 	if reminderInstance != nil {
 		return reminderInstance
 	}
 
+	// Exclusion calendars are kept in memory for the recurrence engine
+	// and reloaded on every replica, leading or not
+	calendars := calendar.NewStore(db)
+	calendars.Start()
+	recurrence.UseCalendars(calendars)
+
+	notifiers := notifier.Configured()
 	reminderInstance = &reminderController{
-		db: db,
+		db:             db,
+		notifiers:      notifiers,
+		relay:          outbox.NewRelay(db, notifiers),
+		queue:          queue,
+		calendars:      calendars,
+		catchUpLimit:   utils.GetEnvIntOrDefault("CATCH_UP_LIMIT", 100),
+		ackResendLimit: utils.GetEnvIntOrDefault("ACK_RESEND_LIMIT", 3),
+		batchSize:      utils.GetEnvIntOrDefault("DISPATCH_BATCH_SIZE", 500),
+		maxBatches:     utils.GetEnvIntOrDefault("DISPATCH_MAX_BATCHES", 100),
 	}
 
 	return reminderInstance
@@ -71,22 +164,75 @@ func (rc *reminderController) CreateRemainder(c *fiber.Ctx) error {
 		})
 	}
 
+	return rc.createReminder(newReminder, c)
+}
+
+func (rc *reminderController) AddTaskReminder(taskUuid uuid.UUID, c *fiber.Ctx) error {
+	var newReminder models.Reminder
+
+	if err := c.BodyParser(&newReminder); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid JSON input",
+		})
+	}
+
+	var task models.Task
+	if result := rc.db.Gorm().Where("task_id = ?", taskUuid).First(&task); result.Error != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Task not found",
+		})
+	}
+
+	newReminder.TaskId = task.TaskId
+	return rc.createReminder(newReminder, c)
+}
+
+func (rc *reminderController) createReminder(newReminder models.Reminder, c *fiber.Ctx) error {
 	newReminder.ReminderId = utils.GenerateNewUUID()
 	if newReminder.StartTime.IsZero() {
 		newReminder.StartTime = time.Now()
 	}
 
+	// Next reminder is always calculated from the schedule
+	newReminder.NextReminder = recurrence.First(newReminder)
+
 	if !utils.ValidateReminder(newReminder) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Invalid reminder",
 		})
 	}
 
-	result := rc.db.Gorm().Create(&newReminder)
+	if !notifier.ValidChannels(newReminder.Channels) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Unknown notification channel",
+		})
+	}
 
-	if result.Error != nil {
-		return result.Error
+	if err := message.Validate(newReminder.Templates, notifier.Registered()); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		if err := lockReminderCalendar(tx, newReminder.Calendar); err != nil {
+			return err
+		}
+
+		return tx.Create(&newReminder).Error
+	})
+
+	if errors.Is(err, errInvalidReminder) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reminder",
+		})
+	}
+
+	if err != nil {
+		return err
 	} else {
+		rc.syncQueue([]uuid.UUID{newReminder.ReminderId})
+
 		message := fmt.Sprintf("Reminder %s (%v) for task (%v) created",
 			newReminder.Reminder, newReminder.ReminderId, newReminder.TaskId)
 		return c.Status(fiber.StatusCreated).SendString(message)
@@ -94,29 +240,22 @@ func (rc *reminderController) CreateRemainder(c *fiber.Ctx) error {
 }
 
 func (rc *reminderController) GetAllReminders(c *fiber.Ctx) error {
+	loc, err := requestedLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time zone",
+		})
+	}
+
 	var reminders []models.Reminder
 	result := rc.db.Gorm().Find(&reminders)
 
 	if result.Error != nil {
 		return result.Error
 	} else {
-		var response []map[string]interface{}
+		var response []fiber.Map
 		for _, reminder := range reminders {
-			response = append(response, map[string]interface{}{
-				"reminder_id":         reminder.ReminderId,
-				"reminder":            reminder.Reminder,
-				"task_id":             reminder.TaskId,
-				"description":         reminder.Description,
-				"start_time":          reminder.StartTime,
-				"frequency":           reminder.Frequency,
-				"repeat_days":         reminder.RepeatDays,
-				"repeat_sameday":      reminder.RepeatSameday,
-				"repeat_until":        reminder.RepeatUntil,
-				"interval":            reminder.Interval,
-				"interval_in_minutes": reminder.IntervalInMinutes,
-				"next_reminder":       reminder.NextReminder,
-				"updated_at":          reminder.UpdatedAt,
-			})
+			response = append(response, reminderDetails(reminder, loc))
 		}
 
 		if response == nil {
@@ -127,52 +266,146 @@ func (rc *reminderController) GetAllReminders(c *fiber.Ctx) error {
 }
 
 func (rc *reminderController) GetReminderByUuid(uuid uuid.UUID, c *fiber.Ctx) error {
+	loc, err := requestedLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time zone",
+		})
+	}
+
 	var reminder models.Reminder
 	result := rc.db.Gorm().First(&reminder, uuid)
 
 	if result.Error != nil {
 		return result.Error
 	} else {
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"reminder_id":         reminder.ReminderId,
-			"reminder":            reminder.Reminder,
-			"task_id":             reminder.TaskId,
-			"description":         reminder.Description,
-			"start_time":          reminder.StartTime,
-			"frequency":           reminder.Frequency,
-			"repeat_days":         reminder.RepeatDays,
-			"repeat_sameday":      reminder.RepeatSameday,
-			"repeat_until":        reminder.RepeatUntil,
-			"interval":            reminder.Interval,
-			"interval_in_minutes": reminder.IntervalInMinutes,
-			"next_reminder":       reminder.NextReminder,
-			"updated_at":          reminder.UpdatedAt,
-		})
+		return c.Status(fiber.StatusOK).JSON(reminderDetails(reminder, loc))
 	}
 }
 
 func (rc *reminderController) GetReminderByTaskUuid(uuid uuid.UUID, c *fiber.Ctx) error {
+	loc, err := requestedLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time zone",
+		})
+	}
+
 	var reminder models.Reminder
 	result := rc.db.Gorm().Where("task_id = ?", uuid).First(&reminder)
 
 	if result.Error != nil {
 		return result.Error
 	} else {
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"reminder_id":         reminder.ReminderId,
-			"reminder":            reminder.Reminder,
-			"task_id":             reminder.TaskId,
-			"description":         reminder.Description,
-			"start_time":          reminder.StartTime,
-			"frequency":           reminder.Frequency,
-			"repeat_days":         reminder.RepeatDays,
-			"repeat_sameday":      reminder.RepeatSameday,
-			"repeat_until":        reminder.RepeatUntil,
-			"interval":            reminder.Interval,
-			"interval_in_minutes": reminder.IntervalInMinutes,
-			"next_reminder":       reminder.NextReminder,
-			"updated_at":          reminder.UpdatedAt,
+		return c.Status(fiber.StatusOK).JSON(reminderDetails(reminder, loc))
+	}
+}
+
+func (rc *reminderController) GetTaskReminders(taskUuid uuid.UUID, c *fiber.Ctx) error {
+	loc, err := requestedLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time zone",
+		})
+	}
+
+	var reminders []models.Reminder
+	result := rc.db.Gorm().Where("task_id = ?", taskUuid).Order("created_at").Find(&reminders)
+
+	if result.Error != nil {
+		return result.Error
+	} else {
+		var response []fiber.Map
+		for _, reminder := range reminders {
+			response = append(response, reminderDetails(reminder, loc))
+		}
+
+		if response == nil {
+			return c.Status(fiber.StatusOK).SendString("Reminders not found")
+		}
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+func (rc *reminderController) PauseReminder(taskUuid uuid.UUID, reminderUuid uuid.UUID, c *fiber.Ctx) error {
+	result := rc.db.Gorm().Model(&models.Reminder{}).
+		Where("task_id = ? AND reminder_id = ?", taskUuid, reminderUuid).
+		Update("paused", true)
+
+	if result.Error != nil {
+		return result.Error
+	} else if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Reminder not found",
 		})
+	} else {
+		rc.syncQueue([]uuid.UUID{reminderUuid})
+
+		message := fmt.Sprintf("Reminder (%v) for task (%v) paused", reminderUuid, taskUuid)
+		return c.Status(fiber.StatusCreated).SendString(message)
+	}
+}
+
+func (rc *reminderController) ResumeReminder(taskUuid uuid.UUID, reminderUuid uuid.UUID, c *fiber.Ctx) error {
+	var reminder models.Reminder
+
+	// Occurrences missed while paused are skipped
+	err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("task_id = ? AND reminder_id = ?", taskUuid, reminderUuid).First(&reminder)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Model(&reminder).Where("reminder_id = ?", reminderUuid).Update("paused", false); result.Error != nil {
+			return result.Error
+		}
+
+		return advanceReminder(tx, reminder, time.Now())
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Reminder not found",
+		})
+	}
+
+	if err != nil {
+		return err
+	} else {
+		rc.syncQueue([]uuid.UUID{reminderUuid})
+
+		message := fmt.Sprintf("Reminder %s (%v) for task (%v) resumed",
+			reminder.Reminder, reminder.ReminderId, reminder.TaskId)
+		return c.Status(fiber.StatusCreated).SendString(message)
+	}
+}
+
+func (rc *reminderController) DeleteReminder(taskUuid uuid.UUID, reminderUuid uuid.UUID, c *fiber.Ctx) error {
+	var reminder models.Reminder
+
+	err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("task_id = ? AND reminder_id = ?", taskUuid, reminderUuid).First(&reminder)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		return DeleteReminders(tx, []uuid.UUID{reminderUuid})
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Reminder not found",
+		})
+	}
+
+	if err != nil {
+		return err
+	} else {
+		rc.syncQueue([]uuid.UUID{reminderUuid})
+
+		message := fmt.Sprintf("Reminder %s (%v) for task (%v) deleted",
+			reminder.Reminder, reminder.ReminderId, reminder.TaskId)
+		return c.Status(fiber.StatusOK).SendString(message)
 	}
 }
 
@@ -184,56 +417,452 @@ func (rc *reminderController) UpdateRemainder(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON"})
 	}
 
-	rc.db.Gorm().Model(&reminder).Where("reminder_id = ?", data["reminder_id"]).Updates(data)
-	result := rc.db.Gorm().Where("reminder_id = ?", data["reminder_id"]).First(&reminder)
+	// Next reminder is calculated from the schedule, not by the client
+	delete(data, "next_reminder")
 
-	if result.Error != nil {
-		return result.Error
+	// Templates are stored as JSON text
+	if templates, ok := data["templates"]; ok {
+		encoded, err := json.Marshal(templates)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid JSON"})
+		}
+		data["templates"] = string(encoded)
+	}
+
+	// Only keep the update when the updated reminder is still valid
+	err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		if result := tx.Model(&reminder).Where("reminder_id = ?", data["reminder_id"]).Updates(data); result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Where("reminder_id = ?", data["reminder_id"]).First(&reminder); result.Error != nil {
+			return result.Error
+		}
+
+		if scheduleChanged(data) {
+			reminder.NextReminder = recurrence.First(reminder)
+			if !utils.ValidateReminder(reminder) {
+				return errInvalidReminder
+			}
+		}
+
+		if _, ok := data["calendar"]; ok {
+			if err := lockReminderCalendar(tx, reminder.Calendar); err != nil {
+				return err
+			}
+		}
+
+		if !notifier.ValidChannels(reminder.Channels) {
+			return errInvalidReminder
+		}
+
+		if err := message.Validate(reminder.Templates, notifier.Registered()); err != nil {
+			return fmt.Errorf("%w: %v", errInvalidTemplate, err)
+		}
+
+		if scheduleChanged(data) {
+			return advanceReminder(tx, reminder, time.Now())
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errInvalidReminder) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reminder",
+		})
+	}
+
+	if errors.Is(err, errInvalidTemplate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err != nil {
+		return err
 	} else {
+		rc.syncQueue([]uuid.UUID{reminder.ReminderId})
+
 		message := fmt.Sprintf("Reminder %s (%v) for task (%v) updated",
 			reminder.Reminder, reminder.ReminderId, reminder.TaskId)
 		return c.Status(fiber.StatusCreated).SendString(message)
 	}
 }
 
-func (rc *reminderController) SendReminder(c *fiber.Ctx) {
-	var reminders []models.Reminder
-	var reminderToSend []kafka.Message
-	result := rc.db.Gorm().Find(&reminders)
+func (rc *reminderController) GetReminderDeliveries(uuid uuid.UUID, c *fiber.Ctx) error {
+	var deliveries []models.ReminderDelivery
 
-	if result.Error == nil {
+	limit := c.QueryInt("limit", 100)
+	if limit <= 0 || limit > 500 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit must be between 1 and 500",
+		})
+	}
 
-		currentDateTime := time.Now()
-		for _, reminder := range reminders {
-			if reminder.NextReminder.Compare(currentDateTime) >= 0 {
-				reminderToSend = append(reminderToSend, kafka.Message{
-					Key:   []byte(reminder.Reminder),
-					Value: []byte(reminder.Description),
-				})
-			}
-		}
+	query := rc.db.Gorm().Where("reminder_id = ?", uuid)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
 
-		// We're ready to send messages
-		topic := "tasker_reminder_notify"
-		partition := 0
+	result := query.Order("attempted_at DESC").Limit(limit).Find(&deliveries)
 
-		conn, err := kafka.DialLeader(context.Background(), "tcp", "localhost:9092", topic, partition)
-		if err != nil {
-			log.Info("failed to dial leader:", err)
+	if result.Error != nil {
+		return result.Error
+	} else {
+		var response []fiber.Map
+		for _, delivery := range deliveries {
+			response = append(response, fiber.Map{
+				"delivery_id":  delivery.DeliveryId,
+				"reminder_id":  delivery.ReminderId,
+				"event_id":     delivery.EventId,
+				"occurrence":   delivery.Occurrence,
+				"channel":      delivery.Channel,
+				"status":       delivery.Status,
+				"error":        delivery.Error,
+				"latency_ms":   delivery.LatencyMs,
+				"attempted_at": delivery.AttemptedAt,
+			})
 		}
 
-		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-		_, err = conn.WriteMessages(
-			reminderToSend...,
-		)
-		if err != nil {
-			log.Info("failed to write messages:", err)
+		if response == nil {
+			return c.Status(fiber.StatusOK).SendString("Deliveries not found")
 		}
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+func (rc *reminderController) SnoozeReminder(uuid uuid.UUID, c *fiber.Ctx) error {
+	var request occurrenceRequest
+
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid JSON input",
+		})
+	}
 
-		if err := conn.Close(); err != nil {
-			log.Info("failed to close writer:", err)
+	// Snooze either by a duration or until a time
+	snoozedUntil := request.Until
+	if request.Duration != "" {
+		duration, err := time.ParseDuration(request.Duration)
+		if err != nil || duration <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid snooze duration",
+			})
 		}
+		until := time.Now().Add(duration)
+		snoozedUntil = &until
+	}
+
+	if snoozedUntil == nil || !snoozedUntil.After(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Snooze duration or a future time is required",
+		})
+	}
+
+	occurrence, err := rc.findOccurrence(uuid, request.Occurrence)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Reminder occurrence not found",
+		})
+	} else if err != nil {
+		return err
+	}
+
+	result := rc.db.Gorm().Model(&models.ReminderOccurrence{}).
+		Where("occurrence_id = ?", occurrence.OccurrenceId).
+		Updates(map[string]interface{}{
+			"status":        models.OccurrenceSnoozed,
+			"snoozed_until": *snoozedUntil,
+			"snooze_count":  gorm.Expr("snooze_count + 1"),
+		})
+
+	if result.Error != nil {
+		return result.Error
+	} else {
+		message := fmt.Sprintf("Reminder (%v) occurrence %v snoozed until %v",
+			uuid, occurrence.Occurrence.Format(time.RFC3339), snoozedUntil.Format(time.RFC3339))
+		return c.Status(fiber.StatusCreated).SendString(message)
+	}
+}
+
+func (rc *reminderController) AcknowledgeReminder(uuid uuid.UUID, c *fiber.Ctx) error {
+	var request occurrenceRequest
+
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&request); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid JSON input",
+			})
+		}
+	}
+
+	occurrence, err := rc.findOccurrence(uuid, request.Occurrence)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Reminder occurrence not found",
+		})
+	} else if err != nil {
+		return err
+	}
+
+	result := rc.db.Gorm().Model(&models.ReminderOccurrence{}).
+		Where("occurrence_id = ?", occurrence.OccurrenceId).
+		Updates(map[string]interface{}{
+			"status":          models.OccurrenceAcknowledged,
+			"acknowledged_at": time.Now(),
+			"snoozed_until":   nil,
+		})
+
+	if result.Error != nil {
+		return result.Error
 	} else {
-		log.Info("No reminder found: ", result.Error)
+		message := fmt.Sprintf("Reminder (%v) occurrence %v acknowledged",
+			uuid, occurrence.Occurrence.Format(time.RFC3339))
+		return c.Status(fiber.StatusCreated).SendString(message)
+	}
+}
+
+func (rc *reminderController) GetReminderOccurrences(uuid uuid.UUID, c *fiber.Ctx) error {
+	var reminder models.Reminder
+	result := rc.db.Gorm().Where("reminder_id = ?", uuid).First(&reminder)
+
+	if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Reminder not found",
+		})
+	} else if result.Error != nil {
+		return result.Error
+	}
+
+	return reminderOccurrences(reminder, c)
+}
+
+func (rc *reminderController) PreviewReminderOccurrences(c *fiber.Ctx) error {
+	var reminder models.Reminder
+
+	if err := c.BodyParser(&reminder); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid JSON input",
+		})
+	}
+
+	// Unsaved reminders only need a schedule to be previewed
+	reminder.ReminderId = utils.GenerateNewUUID()
+	if reminder.TaskId == uuid.Nil {
+		reminder.TaskId = utils.GenerateNewUUID()
+	}
+	if reminder.Reminder == "" {
+		reminder.Reminder = "preview"
+	}
+	if reminder.StartTime.IsZero() {
+		reminder.StartTime = time.Now()
+	}
+	reminder.NextReminder = recurrence.First(reminder)
+
+	if !utils.ValidateReminder(reminder) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid reminder",
+		})
+	}
+
+	return reminderOccurrences(reminder, c)
+}
+
+// Expand a reminder schedule with the from, to, limit and tz query parameters
+func reminderOccurrences(reminder models.Reminder, c *fiber.Ctx) error {
+	loc, err := requestedLocation(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid time zone",
+		})
+	}
+	if loc == nil {
+		if loc, err = recurrence.Location(reminder); err != nil {
+			loc = time.UTC
+		}
+	}
+
+	from := time.Now()
+	if c.Query("from") != "" {
+		if from, err = time.Parse(time.RFC3339, c.Query("from")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid from time",
+			})
+		}
+	}
+
+	var to time.Time
+	if c.Query("to") != "" {
+		if to, err = time.Parse(time.RFC3339, c.Query("to")); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid to time",
+			})
+		}
+	}
+
+	limit := c.QueryInt("limit", 5)
+	if limit <= 0 || limit > 100 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Limit must be between 1 and 100",
+		})
+	}
+
+	occurrences := []time.Time{}
+	for _, occurrence := range recurrence.Between(reminder, from, to, limit) {
+		occurrences = append(occurrences, occurrence.In(loc))
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"occurrences": occurrences,
+	})
+}
+
+// Body of the snooze and acknowledge requests
+type occurrenceRequest struct {
+	Occurrence *time.Time `json:"occurrence"`
+	Duration   string     `json:"duration"`
+	Until      *time.Time `json:"until"`
+}
+
+// Find a delivered or pending occurrence of a reminder,
+// the latest one when no occurrence time is given.
+// Occurrences dropped, missed or acknowledged are not found,
+// so snoozing cannot bring back what was suppressed on purpose.
+func (rc *reminderController) findOccurrence(reminderId uuid.UUID, at *time.Time) (models.ReminderOccurrence, error) {
+	var occurrence models.ReminderOccurrence
+
+	query := rc.db.Gorm().Where("status IN ?", []string{models.OccurrenceFired, models.OccurrenceSnoozed})
+	if at != nil {
+		result := query.
+			Where("occurrence_id = ?", events.OccurrenceId(reminderId, *at)).
+			First(&occurrence)
+		return occurrence, result.Error
+	}
+
+	result := query.
+		Where("reminder_id = ?", reminderId).
+		Order("occurrence DESC").
+		First(&occurrence)
+	return occurrence, result.Error
+}
+
+// DeleteReminders removes reminders with their occurrences,
+// deliveries, escalation steps, quiet hours
+// and outbox messages not yet sent
+func DeleteReminders(tx *gorm.DB, reminderIds []uuid.UUID) error {
+	if len(reminderIds) == 0 {
+		return nil
+	}
+
+	subjects := make([]string, 0, len(reminderIds))
+	for _, reminderId := range reminderIds {
+		subjects = append(subjects, reminderId.String())
+	}
+
+	if err := tx.Where("reminder_id IN ? AND sent_at IS NULL", reminderIds).Delete(&models.Outbox{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("reminder_id IN ?", reminderIds).Delete(&models.ReminderOccurrence{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("reminder_id IN ?", reminderIds).Delete(&models.ReminderDelivery{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("reminder_id IN ?", reminderIds).Delete(&models.EscalationStep{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("scope = ? AND subject IN ?", models.QuietScopeReminder, subjects).Delete(&models.QuietHours{}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("reminder_id IN ?", reminderIds).Delete(&models.DeadLetter{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("reminder_id IN ?", reminderIds).Delete(&models.Reminder{}).Error
+}
+
+// Check if an update touches any field used to calculate the next reminder
+func scheduleChanged(data map[string]interface{}) bool {
+	scheduleFields := []string{
+		"start_time", "frequency", "repeat_days", "repeat_sameday",
+		"repeat_until", "interval", "interval_in_minutes",
+		"rrule", "exdate", "rdate", "time_zone",
+		"calendar", "calendar_policy", "business_day", "cron",
+	}
+
+	for _, field := range scheduleFields {
+		if _, ok := data[field]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Time zone requested by the caller with the tz query parameter,
+// nil when the caller did not ask for one
+func requestedLocation(c *fiber.Ctx) (*time.Location, error) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return nil, nil
+	}
+
+	return time.LoadLocation(tz)
+}
+
+// Reminder details returned by the API,
+// times are rendered in the requested zone or the reminder time zone
+func reminderDetails(reminder models.Reminder, loc *time.Location) fiber.Map {
+	if loc == nil {
+		loc = reminder.StartTime.Location()
+		if reminderLoc, err := recurrence.Location(reminder); err == nil {
+			loc = reminderLoc
+		}
+	}
+
+	reminder.StartTime = reminder.StartTime.In(loc)
+	reminder.UpdatedAt = reminder.UpdatedAt.In(loc)
+	if reminder.RepeatUntil != nil {
+		repeatUntil := reminder.RepeatUntil.In(loc)
+		reminder.RepeatUntil = &repeatUntil
+	}
+	if reminder.NextReminder != nil {
+		nextReminder := reminder.NextReminder.In(loc)
+		reminder.NextReminder = &nextReminder
+	}
+
+	return fiber.Map{
+		"reminder_id":         reminder.ReminderId,
+		"reminder":            reminder.Reminder,
+		"task_id":             reminder.TaskId,
+		"description":         reminder.Description,
+		"start_time":          reminder.StartTime,
+		"frequency":           reminder.Frequency,
+		"repeat_days":         reminder.RepeatDays,
+		"repeat_sameday":      reminder.RepeatSameday,
+		"repeat_until":        reminder.RepeatUntil,
+		"interval":            reminder.Interval,
+		"interval_in_minutes": reminder.IntervalInMinutes,
+		"rrule":               reminder.RRule,
+		"exdate":              reminder.ExDate,
+		"rdate":               reminder.RDate,
+		"time_zone":           reminder.TimeZone,
+		"channels":            reminder.Channels,
+		"recipient":           reminder.Recipient,
+		"catch_up":            reminder.CatchUp,
+		"calendar":            reminder.Calendar,
+		"calendar_policy":     reminder.CalendarPolicy,
+		"business_day":        reminder.BusinessDay,
+		"cron":                reminder.Cron,
+		"templates":           reminder.Templates,
+		"paused":              reminder.Paused,
+		"next_reminder":       reminder.NextReminder,
+		"updated_at":          reminder.UpdatedAt,
 	}
 }
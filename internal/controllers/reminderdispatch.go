@@ -0,0 +1,586 @@
+package controllers
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/delayqueue"
+	"github.com/kevinhartarto/tasker/internal/metrics"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/notifier"
+	"github.com/kevinhartarto/tasker/internal/outbox"
+	"github.com/kevinhartarto/tasker/internal/quiethours"
+	"github.com/kevinhartarto/tasker/internal/recurrence"
+	"github.com/kevinhartarto/tasker/pkg/events"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (rc *reminderController) SendReminder(fencingToken int64) {
+	currentDateTime := time.Now()
+
+	// A deposed leader stops before touching anything
+	if !rc.fenced(fencingToken) {
+		return
+	}
+
+	rc.queueReminders(currentDateTime, fencingToken, nil)
+	rc.queueSnoozed(currentDateTime, fencingToken)
+	rc.queueEscalations(currentDateTime, fencingToken)
+
+	// Publish everything queued, including leftovers of earlier runs
+	rc.publish(fencingToken)
+}
+
+// Upper bound of occurrences kept for a single reminder on catch up,
+// earlier ones are expanded page by page but not recorded
+const (
+	catchUpScanLimit = 10000
+	catchUpPageSize  = 1000
+)
+
+// Wait of a reminder that failed to dispatch from the delay queue
+const queueRetryDelay = time.Minute
+
+var errFenced = errors.New("a newer leader is dispatching")
+
+func (rc *reminderController) Close() {
+	rc.calendars.Stop()
+	notifier.CloseAll(rc.notifiers)
+}
+
+func (rc *reminderController) SendQueuedReminders(fencingToken int64) {
+	if !rc.queue.Enabled() {
+		return
+	}
+
+	currentDateTime := time.Now()
+	reminderIds, err := rc.queue.Due(currentDateTime, rc.batchSize)
+	if err != nil {
+		log.Info("Failed to query delay queue", "message: ", err)
+		return
+	}
+
+	if len(reminderIds) == 0 {
+		return
+	}
+
+	failedIds := rc.queueReminders(currentDateTime, fencingToken, reminderIds)
+
+	// Reminders not dispatched, e.g. paused, deleted or moved in the meantime,
+	// get their database state back. Failed ones wait a while before the next try.
+	rc.syncQueue(reminderIds)
+	for _, reminderId := range failedIds {
+		if err := rc.queue.Schedule(reminderId, currentDateTime.Add(queueRetryDelay)); err != nil {
+			log.Info("Failed to reschedule reminder in delay queue", "reminder", reminderId, "message: ", err)
+		}
+	}
+
+	rc.publish(fencingToken)
+}
+
+// Move due reminders into the outbox, all of them or only the given ones
+// return the reminders that failed
+func (rc *reminderController) queueReminders(currentDateTime time.Time, fencingToken int64, reminderIds []uuid.UUID) []uuid.UUID {
+	windows := loadQuietHours(rc.db)
+
+	return rc.dispatchDue(currentDateTime, fencingToken, reminderIds, func(tx *gorm.DB, reminder models.Reminder, task models.Task) error {
+		occurrences, dropped := dueOccurrences(reminder, currentDateTime)
+		if len(occurrences) == 0 {
+			return advanceReminder(tx, reminder, currentDateTime)
+		}
+
+		// The latest occurrence is on time, earlier ones fell between ticks
+		missed, fired := occurrences[:len(occurrences)-1], occurrences[len(occurrences)-1:]
+		fired, missed = rc.catchUp(reminder, missed, fired, dropped)
+
+		return rc.queueReminder(tx, reminder, task, fired, missed, windows, currentDateTime)
+	})
+}
+
+func (rc *reminderController) CatchUpReminders(grace time.Duration, fencingToken int64) {
+	currentDateTime := time.Now()
+
+	// The queue may have missed changes while nobody was leading
+	rc.rebuildQueue()
+
+	windows := loadQuietHours(rc.db)
+	onTime := currentDateTime.Add(-grace)
+
+	rc.dispatchDue(currentDateTime, fencingToken, nil, func(tx *gorm.DB, reminder models.Reminder, task models.Task) error {
+		occurrences, dropped := dueOccurrences(reminder, currentDateTime)
+
+		// Occurrences within the grace period are on time and always fired
+		var missed, fired []time.Time
+		for _, occurrence := range occurrences {
+			if occurrence.Before(onTime) {
+				missed = append(missed, occurrence)
+			} else {
+				fired = append(fired, occurrence)
+			}
+		}
+		fired, missed = rc.catchUp(reminder, missed, fired, dropped)
+
+		return rc.queueReminder(tx, reminder, task, fired, missed, windows, currentDateTime)
+	})
+}
+
+// Occurrences of a reminder from its next reminder up to the given time,
+// expanded page by page. Only the latest catchUpScanLimit are kept,
+// return them with the number of earlier ones left out
+func dueOccurrences(reminder models.Reminder, until time.Time) ([]time.Time, int) {
+	var occurrences []time.Time
+	dropped := 0
+
+	from := *reminder.NextReminder
+	for {
+		page := recurrence.Between(reminder, from, until, catchUpPageSize)
+
+		occurrences = append(occurrences, page...)
+		if excess := len(occurrences) - catchUpScanLimit; excess > 0 {
+			occurrences = occurrences[excess:]
+			dropped += excess
+		}
+
+		if len(page) < catchUpPageSize {
+			return occurrences, dropped
+		}
+		from = page[len(page)-1].Add(time.Nanosecond)
+	}
+}
+
+// Apply the catch up policy of a reminder to its missed occurrences,
+// dropped ones were missed too but are not recorded
+// return the occurrences to fire and the ones to record as missed
+func (rc *reminderController) catchUp(reminder models.Reminder, missed []time.Time, fired []time.Time, dropped int) ([]time.Time, []time.Time) {
+	switch reminder.CatchUp {
+	case models.CatchUpAll:
+		if skip := len(missed) - rc.catchUpLimit; skip > 0 {
+			fired = slices.Concat(missed[skip:], fired)
+			missed = missed[:skip]
+		} else {
+			fired = slices.Concat(missed, fired)
+			missed = nil
+		}
+	case models.CatchUpSkip:
+	default:
+		if len(fired) == 0 && len(missed) > 0 {
+			fired = missed[len(missed)-1:]
+			missed = missed[:len(missed)-1]
+		}
+	}
+
+	if len(missed) > 0 || dropped > 0 {
+		log.Info("Reminder missed occurrences", "reminder", reminder.ReminderId,
+			"missed", len(missed)+dropped, "fired", len(fired), "not recorded", dropped, "policy", reminder.CatchUp)
+	}
+
+	return fired, missed
+}
+
+// Hand due reminders to queue batch by batch, every batch in a transaction
+// holding row locks on its reminders so other instances skip them.
+// A failing reminder only rolls back its own savepoint and is left
+// for the next tick, a batch of a deposed leader is rolled back as a whole.
+// Without reminder UUIDs every due reminder is dispatched.
+// return the reminders that failed
+func (rc *reminderController) dispatchDue(currentDateTime time.Time, fencingToken int64, reminderIds []uuid.UUID, queue func(*gorm.DB, models.Reminder, models.Task) error) []uuid.UUID {
+	tickStart := time.Now()
+	var failedIds []uuid.UUID
+	dispatched := 0
+
+	for batch := 0; batch < rc.maxBatches; batch++ {
+		batchStart := time.Now()
+		failed := 0
+		var lockedIds []uuid.UUID
+
+		err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+			if err := fence(tx, fencingToken); err != nil {
+				return err
+			}
+
+			dueReminders, err := lockDueReminders(tx, currentDateTime, rc.batchSize, reminderIds, failedIds)
+			if err != nil {
+				return err
+			}
+			for _, reminder := range dueReminders {
+				lockedIds = append(lockedIds, reminder.ReminderId)
+			}
+
+			tasks := reminderTasks(tx, dueReminders)
+			for _, reminder := range dueReminders {
+				err := tx.Transaction(func(tx *gorm.DB) error {
+					return queue(tx, reminder, tasks[reminder.TaskId])
+				})
+				if err != nil {
+					log.Info("Failed to queue reminder", "reminder", reminder.ReminderId, "message: ", err)
+					failedIds = append(failedIds, reminder.ReminderId)
+					failed++
+				}
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			log.Info("Failed to dispatch due reminders", "message: ", err)
+			break
+		}
+
+		locked := len(lockedIds)
+		dispatched += locked - failed
+		metrics.RecordBatch(locked-failed, failed, time.Since(batchStart))
+
+		// Dispatched reminders moved on to their next occurrence
+		rc.syncQueue(lockedIds)
+
+		if locked < rc.batchSize {
+			break
+		}
+	}
+
+	metrics.RecordTick(dispatched, time.Since(tickStart))
+	return failedIds
+}
+
+// Check this dispatcher still holds the newest fencing token
+// return false, logging why, when it must stop dispatching
+func (rc *reminderController) fenced(fencingToken int64) bool {
+	if err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error { return fence(tx, fencingToken) }); err != nil {
+		log.Info("Not dispatching reminders", "token", fencingToken, "message: ", err)
+		return false
+	}
+
+	return true
+}
+
+// Publish the outbox, unless a newer leader took over in the meantime
+func (rc *reminderController) publish(fencingToken int64) {
+	if rc.fenced(fencingToken) {
+		rc.relay.Publish()
+	}
+}
+
+// Record the fencing token of this dispatcher,
+// fails when a newer leader already wrote with a higher token.
+// The fence row stays locked until the transaction ends,
+// so writes of an older leader cannot interleave.
+// Token 0 comes without leader election and is not fenced.
+func fence(tx *gorm.DB, fencingToken int64) error {
+	if fencingToken == 0 {
+		return nil
+	}
+
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}},
+		DoUpdates: clause.AssignmentColumns([]string{"token", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "dispatcher_fence.token <= excluded.token"},
+		}},
+	}).Create(&models.DispatcherFence{Name: models.ReminderDispatcherFence, Token: fencingToken})
+
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errFenced
+	}
+
+	return nil
+}
+
+// Lock the next batch of reminders not paused with a next reminder
+// at or before the given time, skipping rows locked by other instances
+func lockDueReminders(tx *gorm.DB, currentDateTime time.Time, limit int, reminderIds []uuid.UUID, excludedIds []uuid.UUID) ([]models.Reminder, error) {
+	var dueReminders []models.Reminder
+
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("NOT paused AND next_reminder <= ?", currentDateTime)
+	if reminderIds != nil {
+		query = query.Where("reminder_id IN ?", reminderIds)
+	}
+	if len(excludedIds) > 0 {
+		query = query.Where("reminder_id NOT IN ?", excludedIds)
+	}
+
+	result := query.Order("next_reminder").Limit(limit).Find(&dueReminders)
+	return dueReminders, result.Error
+}
+
+// Queue the fired occurrences of a reminder, record the missed ones
+// and move the reminder forward together,
+// a crash in between can neither lose nor repeat an occurrence
+func (rc *reminderController) queueReminder(tx *gorm.DB, reminder models.Reminder, task models.Task, fired []time.Time, missed []time.Time, windows []quiethours.Window, currentDateTime time.Time) error {
+	channels := notifier.Channels(reminder, rc.notifiers)
+	var occurrences []models.ReminderOccurrence
+
+	// Quiet hours defer the occurrence like a snooze or drop it
+	quiet, policy, until := quiethours.Check(windows, reminder, currentDateTime)
+	for _, at := range fired {
+		notification := notifier.NewNotification(reminder, task, at, channels)
+		occurrence := newOccurrence(notification, currentDateTime)
+
+		switch {
+		case quiet && policy == models.QuietPolicyDrop:
+			occurrence.Status = models.OccurrenceDropped
+		case quiet:
+			occurrence.Status = models.OccurrenceSnoozed
+			occurrence.SnoozedUntil = &until
+		default:
+			if err := outbox.Enqueue(tx, []notifier.Notification{notification}); err != nil {
+				return err
+			}
+		}
+
+		occurrences = append(occurrences, occurrence)
+	}
+
+	for _, at := range missed {
+		occurrence := newOccurrence(notifier.NewNotification(reminder, task, at, channels), currentDateTime)
+		occurrence.Status = models.OccurrenceMissed
+		occurrences = append(occurrences, occurrence)
+	}
+
+	if len(occurrences) > 0 {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&occurrences).Error; err != nil {
+			return err
+		}
+	}
+
+	return advanceReminder(tx, reminder, currentDateTime)
+}
+
+// Fire snoozed occurrences again once their snooze is over,
+// the reminder schedule itself is left untouched
+func (rc *reminderController) queueSnoozed(currentDateTime time.Time, fencingToken int64) {
+	var occurrences []models.ReminderOccurrence
+	result := rc.db.Gorm().
+		Where("status = ? AND snoozed_until <= ?", models.OccurrenceSnoozed, currentDateTime).
+		Find(&occurrences)
+
+	if result.Error != nil {
+		log.Info("Failed to query snoozed reminders", "message: ", result.Error)
+		return
+	}
+
+	if len(occurrences) == 0 {
+		return
+	}
+
+	var reminderIds []uuid.UUID
+	for _, occurrence := range occurrences {
+		reminderIds = append(reminderIds, occurrence.ReminderId)
+	}
+
+	var reminders []models.Reminder
+	if result := rc.db.Gorm().Where("reminder_id IN ? AND NOT paused", reminderIds).Find(&reminders); result.Error != nil {
+		log.Info("Failed to query snoozed reminders", "message: ", result.Error)
+		return
+	}
+
+	reminderById := map[uuid.UUID]models.Reminder{}
+	for _, reminder := range reminders {
+		reminderById[reminder.ReminderId] = reminder
+	}
+	tasks := reminderTasks(rc.db.Gorm(), reminders)
+	windows := loadQuietHours(rc.db)
+
+	for _, occurrence := range occurrences {
+		reminder, ok := reminderById[occurrence.ReminderId]
+		if !ok {
+			continue
+		}
+
+		// Snoozes ending inside quiet hours wait for the quiet hours too
+		if quiet, policy, until := quiethours.Check(windows, reminder, currentDateTime); quiet {
+			if err := rc.deferOccurrence(occurrence, policy, until, fencingToken); errors.Is(err, errFenced) {
+				log.Info("Not dispatching reminders", "token", fencingToken, "message: ", err)
+				return
+			}
+			continue
+		}
+
+		notification := snoozedNotification(reminder, tasks[reminder.TaskId], occurrence, notifier.Channels(reminder, rc.notifiers))
+
+		err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+			if err := fence(tx, fencingToken); err != nil {
+				return err
+			}
+
+			if err := outbox.Enqueue(tx, []notifier.Notification{notification}); err != nil {
+				return err
+			}
+
+			return tx.Model(&models.ReminderOccurrence{}).
+				Where("occurrence_id = ? AND status = ?", occurrence.OccurrenceId, models.OccurrenceSnoozed).
+				Updates(map[string]interface{}{
+					"status":           models.OccurrenceFired,
+					"fired_at":         currentDateTime,
+					"snoozed_until":    nil,
+					"escalation_level": 0,
+				}).Error
+		})
+		if errors.Is(err, errFenced) {
+			log.Info("Not dispatching reminders", "token", fencingToken, "message: ", err)
+			return
+		}
+		if err != nil {
+			log.Info("Failed to queue snoozed reminder", "reminder", reminder.ReminderId, "message: ", err)
+		}
+	}
+}
+
+// The occurrence sent again once its snooze is over,
+// every snooze fires as an event of its own
+func snoozedNotification(reminder models.Reminder, task models.Task, occurrence models.ReminderOccurrence, channels []string) notifier.Notification {
+	notification := notifier.NewNotification(reminder, task, occurrence.Occurrence, channels)
+	if occurrence.SnoozeCount > 0 {
+		notification.Event.EventId = events.SnoozeId(occurrence.OccurrenceId, occurrence.SnoozeCount)
+		notification.Event.SnoozeCount = occurrence.SnoozeCount
+	}
+
+	return notification
+}
+
+// Tasks of the given reminders by task UUID
+func reminderTasks(tx *gorm.DB, reminders []models.Reminder) map[uuid.UUID]models.Task {
+	var taskIds []uuid.UUID
+	for _, reminder := range reminders {
+		taskIds = append(taskIds, reminder.TaskId)
+	}
+
+	var tasks []models.Task
+	if result := tx.Where("task_id IN ?", taskIds).Find(&tasks); result.Error != nil {
+		log.Info("Failed to query reminder tasks", "message: ", result.Error)
+	}
+
+	taskById := map[uuid.UUID]models.Task{}
+	for _, task := range tasks {
+		taskById[task.TaskId] = task
+	}
+
+	return taskById
+}
+
+// Keep quiet hours on a snoozed occurrence
+func (rc *reminderController) deferOccurrence(occurrence models.ReminderOccurrence, policy string, until time.Time, fencingToken int64) error {
+	updates := map[string]interface{}{"snoozed_until": until}
+	if policy == models.QuietPolicyDrop {
+		updates = map[string]interface{}{"status": models.OccurrenceDropped, "snoozed_until": nil}
+	}
+
+	err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		if err := fence(tx, fencingToken); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.ReminderOccurrence{}).
+			Where("occurrence_id = ? AND status = ?", occurrence.OccurrenceId, models.OccurrenceSnoozed).
+			Updates(updates).Error
+	})
+	if err != nil && !errors.Is(err, errFenced) {
+		log.Info("Failed to defer snoozed reminder", "reminder", occurrence.ReminderId, "message: ", err)
+	}
+
+	return err
+}
+
+// Track a fired occurrence so it can be snoozed or acknowledged
+func newOccurrence(notification notifier.Notification, firedAt time.Time) models.ReminderOccurrence {
+	return models.ReminderOccurrence{
+		OccurrenceId: notification.Event.OccurrenceId,
+		ReminderId:   notification.Event.ReminderId,
+		Occurrence:   notification.Event.Occurrence,
+		Status:       models.OccurrenceFired,
+		FiredAt:      firedAt,
+	}
+}
+
+// Persist the next occurrence after the given time,
+// clears next reminder once the schedule has ended
+func advanceReminder(tx *gorm.DB, reminder models.Reminder, after time.Time) error {
+	nextReminder := recurrence.Next(reminder, after)
+
+	return tx.Model(&models.Reminder{}).
+		Where("reminder_id = ?", reminder.ReminderId).
+		Update("next_reminder", nextReminder).Error
+}
+
+func (rc *reminderController) syncQueue(reminderIds []uuid.UUID) {
+	syncDelayQueue(rc.db, rc.queue, reminderIds)
+}
+
+// Bring the delay queue in line with the database state of the given reminders,
+// reminders no longer found are taken out
+func syncDelayQueue(db database.Database, queue delayqueue.Queue, reminderIds []uuid.UUID) {
+	if !queue.Enabled() || len(reminderIds) == 0 {
+		return
+	}
+
+	var reminders []models.Reminder
+	result := db.Gorm().Select("reminder_id", "next_reminder", "paused").
+		Where("reminder_id IN ?", reminderIds).
+		Find(&reminders)
+	if result.Error != nil {
+		log.Info("Failed to query reminders for delay queue", "message: ", result.Error)
+		return
+	}
+
+	reminderById := map[uuid.UUID]models.Reminder{}
+	for _, reminder := range reminders {
+		reminderById[reminder.ReminderId] = reminder
+	}
+
+	for _, reminderId := range reminderIds {
+		var err error
+		if reminder, ok := reminderById[reminderId]; ok && !reminder.Paused && reminder.NextReminder != nil {
+			err = queue.Schedule(reminderId, *reminder.NextReminder)
+		} else {
+			err = queue.Remove(reminderId)
+		}
+
+		if err != nil {
+			log.Info("Failed to update delay queue", "reminder", reminderId, "message: ", err)
+		}
+	}
+}
+
+// Add every scheduled reminder to the delay queue, page by page
+func (rc *reminderController) rebuildQueue() {
+	if !rc.queue.Enabled() {
+		return
+	}
+
+	queued := 0
+	lastId := uuid.Nil
+	for {
+		var reminders []models.Reminder
+		result := rc.db.Gorm().Select("reminder_id", "next_reminder").
+			Where("NOT paused AND next_reminder IS NOT NULL AND reminder_id > ?", lastId).
+			Order("reminder_id").
+			Limit(rc.batchSize).
+			Find(&reminders)
+		if result.Error != nil {
+			log.Info("Failed to rebuild delay queue", "message: ", result.Error)
+			return
+		}
+
+		for _, reminder := range reminders {
+			if err := rc.queue.Schedule(reminder.ReminderId, *reminder.NextReminder); err != nil {
+				log.Info("Failed to rebuild delay queue", "message: ", err)
+				return
+			}
+			lastId = reminder.ReminderId
+		}
+		queued += len(reminders)
+
+		if len(reminders) < rc.batchSize {
+			break
+		}
+	}
+
+	log.Info("Delay queue rebuilt", "reminders", queued)
+}
@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/notifier"
+	"github.com/kevinhartarto/tasker/internal/outbox"
+	"github.com/kevinhartarto/tasker/internal/quiethours"
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"github.com/kevinhartarto/tasker/pkg/events"
+	"gorm.io/gorm"
+)
+
+// Escalations overdue by longer than this are given up,
+// e.g. after tasker was down for a while
+const escalationGrace = time.Hour
+
+func (rc *reminderController) SetReminderEscalation(uuid uuid.UUID, c *fiber.Ctx) error {
+	var steps []models.EscalationStep
+
+	if err := c.BodyParser(&steps); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid JSON input",
+		})
+	}
+
+	if err := validateEscalation(steps); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("reminder_id = ?", uuid).First(&models.Reminder{}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("reminder_id = ?", uuid).Delete(&models.EscalationStep{}).Error; err != nil {
+			return err
+		}
+
+		if len(steps) == 0 {
+			return nil
+		}
+
+		for i := range steps {
+			steps[i].EscalationStepId = utils.GenerateNewUUID()
+			steps[i].ReminderId = uuid
+			steps[i].Step = i + 1
+		}
+
+		return tx.Create(&steps).Error
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Reminder not found",
+		})
+	}
+
+	if err != nil {
+		return err
+	} else {
+		message := fmt.Sprintf("Reminder (%v) escalation set with %d steps", uuid, len(steps))
+		return c.Status(fiber.StatusOK).SendString(message)
+	}
+}
+
+func (rc *reminderController) GetReminderEscalation(uuid uuid.UUID, c *fiber.Ctx) error {
+	var steps []models.EscalationStep
+	result := rc.db.Gorm().Where("reminder_id = ?", uuid).Order("step").Find(&steps)
+
+	if result.Error != nil {
+		return result.Error
+	} else if len(steps) == 0 {
+		return c.Status(fiber.StatusOK).SendString("Escalation steps not found")
+	} else {
+		return c.Status(fiber.StatusOK).JSON(steps)
+	}
+}
+
+// Steps must wait longer than the step before them,
+// channel steps need channels and recipient steps a recipient
+func validateEscalation(steps []models.EscalationStep) error {
+	after := 0
+
+	for i, step := range steps {
+		if step.AfterMinutes <= after {
+			return fmt.Errorf("step %d must wait longer than %d minutes", i+1, after)
+		}
+		after = step.AfterMinutes
+
+		switch step.Action {
+		case models.EscalationResend:
+		case models.EscalationChannel:
+			if step.Channels == "" {
+				return fmt.Errorf("step %d needs channels", i+1)
+			}
+		case models.EscalationRecipient:
+			if step.Recipient == "" {
+				return fmt.Errorf("step %d needs a recipient", i+1)
+			}
+		default:
+			return fmt.Errorf("step %d has unknown action %q", i+1, step.Action)
+		}
+
+		if !notifier.ValidChannels(step.Channels) {
+			return fmt.Errorf("step %d has unknown channels %q", i+1, step.Channels)
+		}
+
+		if !slices.Contains([]string{"", models.LevelNotify, models.LevelAlert}, step.Level) {
+			return fmt.Errorf("step %d has unknown level %q", i+1, step.Level)
+		}
+	}
+
+	return nil
+}
+
+// Take the next escalation step of fired occurrences
+// nobody acknowledged in time, one step per occurrence and run
+func (rc *reminderController) queueEscalations(currentDateTime time.Time, fencingToken int64) {
+	var steps []models.EscalationStep
+	if result := rc.db.Gorm().Order("step").Find(&steps); result.Error != nil {
+		log.Info("Failed to query escalation steps", "message: ", result.Error)
+		return
+	}
+
+	if len(steps) == 0 {
+		return
+	}
+
+	stepsByReminder := map[uuid.UUID][]models.EscalationStep{}
+	var reminderIds []uuid.UUID
+	longest := 0
+	for _, step := range steps {
+		if _, ok := stepsByReminder[step.ReminderId]; !ok {
+			reminderIds = append(reminderIds, step.ReminderId)
+		}
+		stepsByReminder[step.ReminderId] = append(stepsByReminder[step.ReminderId], step)
+		longest = max(longest, step.AfterMinutes)
+	}
+
+	since := currentDateTime.Add(-time.Duration(longest)*time.Minute - escalationGrace)
+	var occurrences []models.ReminderOccurrence
+	result := rc.db.Gorm().
+		Where("status = ? AND reminder_id IN ? AND fired_at >= ?", models.OccurrenceFired, reminderIds, since).
+		Find(&occurrences)
+	if result.Error != nil {
+		log.Info("Failed to query unacknowledged reminders", "message: ", result.Error)
+		return
+	}
+
+	if len(occurrences) == 0 {
+		return
+	}
+
+	var reminders []models.Reminder
+	if result := rc.db.Gorm().Where("reminder_id IN ? AND NOT paused", reminderIds).Find(&reminders); result.Error != nil {
+		log.Info("Failed to query escalated reminders", "message: ", result.Error)
+		return
+	}
+
+	reminderById := map[uuid.UUID]models.Reminder{}
+	for _, reminder := range reminders {
+		reminderById[reminder.ReminderId] = reminder
+	}
+	tasks := reminderTasks(rc.db.Gorm(), reminders)
+	windows := loadQuietHours(rc.db)
+
+	for _, occurrence := range occurrences {
+		reminder, ok := reminderById[occurrence.ReminderId]
+		reminderSteps := stepsByReminder[occurrence.ReminderId]
+		if !ok || occurrence.EscalationLevel >= len(reminderSteps) {
+			continue
+		}
+
+		step := reminderSteps[occurrence.EscalationLevel]
+		due := occurrence.FiredAt.Add(time.Duration(step.AfterMinutes) * time.Minute)
+		if due.After(currentDateTime) || due.Before(currentDateTime.Add(-escalationGrace)) {
+			continue
+		}
+
+		// Quiet hours of whoever the step notifies apply like on any other firing
+		escalated := reminder
+		if step.Action == models.EscalationRecipient {
+			escalated.Recipient = step.Recipient
+		}
+		if quiet, policy, until := quiethours.Check(windows, escalated, currentDateTime); quiet {
+			if err := rc.deferEscalation(occurrence, step, policy, until, fencingToken); errors.Is(err, errFenced) {
+				log.Info("Not dispatching reminders", "token", fencingToken, "message: ", err)
+				return
+			}
+			continue
+		}
+
+		notification := rc.escalationNotification(reminder, tasks[reminder.TaskId], occurrence, step)
+		err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+			if err := fence(tx, fencingToken); err != nil {
+				return err
+			}
+
+			if err := outbox.Enqueue(tx, []notifier.Notification{notification}); err != nil {
+				return err
+			}
+
+			return tx.Model(&models.ReminderOccurrence{}).
+				Where("occurrence_id = ? AND status = ? AND escalation_level = ?",
+					occurrence.OccurrenceId, models.OccurrenceFired, occurrence.EscalationLevel).
+				Update("escalation_level", step.Step).Error
+		})
+		if errors.Is(err, errFenced) {
+			log.Info("Not dispatching reminders", "token", fencingToken, "message: ", err)
+			return
+		}
+		if err != nil {
+			log.Info("Failed to escalate reminder", "reminder", reminder.ReminderId, "message: ", err)
+			continue
+		}
+
+		log.Info("Reminder escalated", "reminder", reminder.ReminderId, "step", step.Step, "action", step.Action)
+	}
+}
+
+// Quiet hours drop the escalation step, or hold the occurrence like a snooze
+// until they end, when it fires again and escalates from the start
+func (rc *reminderController) deferEscalation(occurrence models.ReminderOccurrence, step models.EscalationStep, policy string, until time.Time, fencingToken int64) error {
+	updates := escalationDeferral(occurrence, step, policy, until)
+
+	err := rc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		if err := fence(tx, fencingToken); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.ReminderOccurrence{}).
+			Where("occurrence_id = ? AND status = ? AND escalation_level = ?",
+				occurrence.OccurrenceId, models.OccurrenceFired, occurrence.EscalationLevel).
+			Updates(updates).Error
+	})
+	if err != nil {
+		if !errors.Is(err, errFenced) {
+			log.Info("Failed to defer escalation", "reminder", occurrence.ReminderId, "message: ", err)
+		}
+		return err
+	}
+
+	log.Info("Escalation held by quiet hours", "reminder", occurrence.ReminderId, "step", step.Step, "policy", policy)
+	return nil
+}
+
+// Occurrence updates for an escalation step held by quiet hours.
+// A held occurrence counts as snoozed, so it fires again
+// and escalates under event ids it was never sent with.
+func escalationDeferral(occurrence models.ReminderOccurrence, step models.EscalationStep, policy string, until time.Time) map[string]interface{} {
+	if policy == models.QuietPolicyDrop {
+		return map[string]interface{}{"escalation_level": step.Step}
+	}
+
+	return map[string]interface{}{
+		"status":        models.OccurrenceSnoozed,
+		"snoozed_until": until,
+		"snooze_count":  occurrence.SnoozeCount + 1,
+	}
+}
+
+// The occurrence sent again as the given escalation step
+func (rc *reminderController) escalationNotification(reminder models.Reminder, task models.Task, occurrence models.ReminderOccurrence, step models.EscalationStep) notifier.Notification {
+	channels := notifier.Channels(reminder, rc.notifiers)
+	level := models.LevelNotify
+
+	switch step.Action {
+	case models.EscalationChannel:
+		channels = notifier.ParseChannels(step.Channels)
+		level = models.LevelAlert
+	case models.EscalationRecipient:
+		reminder.Recipient = step.Recipient
+		if step.Channels != "" {
+			channels = notifier.ParseChannels(step.Channels)
+		}
+	}
+
+	if step.Level != "" {
+		level = step.Level
+	}
+
+	notification := notifier.NewNotification(reminder, task, occurrence.Occurrence, channels)
+
+	// Escalations of a snoozed occurrence follow its latest firing
+	eventId := occurrence.OccurrenceId
+	if occurrence.SnoozeCount > 0 {
+		eventId = events.SnoozeId(occurrence.OccurrenceId, occurrence.SnoozeCount)
+		notification.Event.SnoozeCount = occurrence.SnoozeCount
+	}
+
+	notification.Event.EventId = events.EscalationId(eventId, step.Step)
+	notification.Event.Escalation = step.Step
+	notification.Event.Level = level
+
+	return notification
+}
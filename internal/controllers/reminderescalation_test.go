@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/notifier"
+)
+
+// Outbox rows are unique by event and channel,
+// a notification reusing a sent event id is never delivered
+type outboxKeys map[string]bool
+
+func (keys outboxKeys) enqueue(t *testing.T, notification notifier.Notification) {
+	t.Helper()
+
+	for _, channel := range notification.Event.Channels {
+		key := notification.Event.EventId.String() + "/" + channel
+		if keys[key] {
+			t.Fatalf("event %v on %s already in the outbox", notification.Event.EventId, channel)
+		}
+		keys[key] = true
+	}
+}
+
+func TestDeferredEscalationFiresAsNewEvents(t *testing.T) {
+	rc := &reminderController{}
+	reminder := models.Reminder{ReminderId: uuid.New(), TaskId: uuid.New(), Reminder: "Standup", Channels: "stdout"}
+	task := models.Task{TaskId: reminder.TaskId, Task: "Report"}
+	steps := []models.EscalationStep{
+		{Step: 1, AfterMinutes: 10, Action: models.EscalationResend},
+		{Step: 2, AfterMinutes: 20, Action: models.EscalationResend},
+	}
+	channels := []string{"stdout"}
+
+	at := time.Date(2024, 3, 4, 21, 0, 0, 0, time.UTC)
+	fired := notifier.NewNotification(reminder, task, at, channels)
+	occurrence := newOccurrence(fired, at)
+
+	sent := outboxKeys{}
+	sent.enqueue(t, fired)
+	sent.enqueue(t, rc.escalationNotification(reminder, task, occurrence, steps[0]))
+	occurrence.EscalationLevel = 1
+
+	// Quiet hours hold the second step twice in a row
+	for hold := 1; hold <= 2; hold++ {
+		until := at.Add(time.Duration(hold) * 10 * time.Hour)
+		updates := escalationDeferral(occurrence, steps[occurrence.EscalationLevel], models.QuietPolicyDefer, until)
+		if updates["status"] != models.OccurrenceSnoozed {
+			t.Fatalf("escalationDeferral() status = %v, want %v", updates["status"], models.OccurrenceSnoozed)
+		}
+
+		// Fired again once quiet hours end, escalating from the start
+		occurrence.SnoozeCount = updates["snooze_count"].(int)
+		occurrence.EscalationLevel = 0
+		sent.enqueue(t, snoozedNotification(reminder, task, occurrence, channels))
+		sent.enqueue(t, rc.escalationNotification(reminder, task, occurrence, steps[0]))
+		occurrence.EscalationLevel = 1
+	}
+
+	if occurrence.SnoozeCount != 2 {
+		t.Errorf("SnoozeCount = %d, want 2", occurrence.SnoozeCount)
+	}
+}
+
+func TestDroppedEscalationMovesOn(t *testing.T) {
+	occurrence := models.ReminderOccurrence{EscalationLevel: 1}
+	step := models.EscalationStep{Step: 2}
+
+	updates := escalationDeferral(occurrence, step, models.QuietPolicyDrop, time.Now())
+	if len(updates) != 1 || updates["escalation_level"] != 2 {
+		t.Errorf("escalationDeferral() = %v, want escalation_level 2 only", updates)
+	}
+}
@@ -2,13 +2,16 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/delayqueue"
 	"github.com/kevinhartarto/tasker/internal/models"
 	"github.com/kevinhartarto/tasker/internal/utils"
+	"gorm.io/gorm"
 )
 
 type TaskController interface {
@@ -42,22 +45,28 @@ type TaskController interface {
 
 	// Change task status to finished
 	TaskFinished(fiber.Ctx) error
+
+	// Delete a task by uuid
+	// reminders of the task are deleted with it
+	DeleteTask(uuid.UUID, fiber.Ctx) error
 }
 
 type taskController struct {
-	db database.Database
+	db    database.Database
+	queue delayqueue.Queue
 }
 
 var taskInstance *taskController
 
-func NewTaskController(db database.Database) *taskController {
+func NewTaskController(db database.Database, queue delayqueue.Queue) *taskController {
 
 	if taskInstance != nil {
 		return taskInstance
 	}
 
 	taskInstance = &taskController{
-		db: db,
+		db:    db,
+		queue: queue,
 	}
 
 	return taskInstance
@@ -104,6 +113,8 @@ func (tc *taskController) GetTasks(c *fiber.Ctx) error {
 				"task_id":     task.TaskId,
 				"task":        task.Task,
 				"description": task.Description,
+				"due_date":    task.DueDate,
+				"tags":        task.Tags,
 			})
 		}
 
@@ -129,6 +140,8 @@ func (tc *taskController) GetFinishedTasks(c *fiber.Ctx) error {
 				"task_id":     task.TaskId,
 				"task":        task.Task,
 				"description": task.Description,
+				"due_date":    task.DueDate,
+				"tags":        task.Tags,
 			})
 		}
 
@@ -151,6 +164,8 @@ func (tc *taskController) GetTaskByUuid(uuid uuid.UUID, c *fiber.Ctx) error {
 			"task_id":     task.TaskId,
 			"task":        task.Task,
 			"description": task.Description,
+			"due_date":    task.DueDate,
+			"tags":        task.Tags,
 			"finished":    task.Finished,
 		})
 	}
@@ -247,3 +262,44 @@ func (tc *taskController) TaskFinished(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusCreated).SendString(message)
 	}
 }
+
+func (tc *taskController) DeleteTask(taskUuid uuid.UUID, c *fiber.Ctx) error {
+	var task models.Task
+	var reminderIds []uuid.UUID
+
+	err := tc.db.Gorm().Transaction(func(tx *gorm.DB) error {
+		if result := tx.Where("task_id = ?", taskUuid).First(&task); result.Error != nil {
+			return result.Error
+		}
+
+		if result := tx.Model(&models.Reminder{}).Where("task_id = ?", taskUuid).Pluck("reminder_id", &reminderIds); result.Error != nil {
+			return result.Error
+		}
+
+		if err := DeleteReminders(tx, reminderIds); err != nil {
+			return err
+		}
+
+		return tx.Where("task_id = ?", taskUuid).Delete(&models.Task{}).Error
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Task not found",
+		})
+	}
+
+	if err != nil {
+		return err
+	} else {
+		// Deleted reminders must not linger in the delay queue
+		for _, reminderId := range reminderIds {
+			if err := tc.queue.Remove(reminderId); err != nil {
+				log.Info("Failed to update delay queue", "reminder", reminderId, "message: ", err)
+			}
+		}
+
+		message := fmt.Sprintf("Task %s (%v) deleted", task.Task, task.TaskId)
+		return c.Status(fiber.StatusOK).SendString(message)
+	}
+}
@@ -57,6 +57,8 @@ func Start() Database {
 			TablePrefix:   "tasker.",
 			SingularTable: true,
 		},
+		// Unique violations surface as gorm.ErrDuplicatedKey
+		TranslateError: true,
 	}
 
 	gormDB, err := gorm.Open(taskerDialector, &taskerConfig)
@@ -66,6 +68,12 @@ func Start() Database {
 		os.Exit(1)
 	}
 
+	if err := migrate(gormDB); err != nil {
+		log.Error("Failed to migrate tasker schema, closing...", "message: ", err)
+		pgx.Close()
+		os.Exit(1)
+	}
+
 	gormService = &database{
 		connection: pgx,
 		gorm:       gormDB,
@@ -0,0 +1,76 @@
+package database
+
+import (
+	"github.com/kevinhartarto/tasker/internal/models"
+	"gorm.io/gorm"
+)
+
+// Columns added to the reminder table after the initial schema
+var reminderColumns = []string{
+	"RRule",
+	"ExDate",
+	"RDate",
+	"TimeZone",
+	"Channels",
+	"Paused",
+	"Recipient",
+	"CatchUp",
+	"Calendar",
+	"CalendarPolicy",
+	"BusinessDay",
+	"Cron",
+	"Templates",
+}
+
+// Columns added to the task table after the initial schema
+var taskColumns = []string{
+	"DueDate",
+	"Tags",
+}
+
+// Bring the tasker schema up to date with the models,
+// existing tables only get their missing columns added
+func migrate(gormDB *gorm.DB) error {
+	migrator := gormDB.Migrator()
+
+	for _, column := range reminderColumns {
+		if migrator.HasColumn(&models.Reminder{}, column) {
+			continue
+		}
+
+		if err := migrator.AddColumn(&models.Reminder{}, column); err != nil {
+			return err
+		}
+	}
+
+	// Due reminders are looked up by next reminder
+	if !migrator.HasIndex(&models.Reminder{}, "idx_reminder_due") {
+		if err := migrator.CreateIndex(&models.Reminder{}, "idx_reminder_due"); err != nil {
+			return err
+		}
+	}
+
+	for _, column := range taskColumns {
+		if migrator.HasColumn(&models.Task{}, column) {
+			continue
+		}
+
+		if err := migrator.AddColumn(&models.Task{}, column); err != nil {
+			return err
+		}
+	}
+
+	// Tables owned by tasker
+	return migrator.AutoMigrate(
+		&models.Outbox{},
+		&models.ReminderDelivery{},
+		&models.ReminderOccurrence{},
+		&models.QuietHours{},
+		&models.Calendar{},
+		&models.CalendarDate{},
+		&models.EscalationStep{},
+		&models.DeadLetter{},
+		&models.DispatcherFence{},
+		&models.CoalesceWindow{},
+	)
+}
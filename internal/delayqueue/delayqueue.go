@@ -0,0 +1,145 @@
+package delayqueue
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+type Queue interface {
+
+	// Check if the delay queue is in use
+	// all other methods do nothing when it is not
+	Enabled() bool
+
+	// How often the queue is checked for due reminders
+	Tick() time.Duration
+
+	// Add a reminder or move it to its next fire time
+	Schedule(uuid.UUID, time.Time) error
+
+	// Take a reminder out of the queue
+	Remove(uuid.UUID) error
+
+	// Query reminders due at the given time, earliest first
+	// return at most limit reminder UUIDs
+	Due(time.Time, int) ([]uuid.UUID, error)
+}
+
+// Sorted set of reminder ids scored by their next fire time in milliseconds.
+// The database stays the source of truth, the queue only tells
+// the dispatcher when to look.
+type redisQueue struct {
+	client  *redis.Client
+	enabled bool
+	key     string
+	tick    time.Duration
+}
+
+// Redis calls are short, a slow Redis must not hold up the API
+const redisTimeout = 2 * time.Second
+
+var (
+	queueInstance *redisQueue
+	log           = logger.GetLogger()
+)
+
+// Delay queue on DELAY_QUEUE_KEY, enabled with DELAY_QUEUE=1
+// and checked every DELAY_QUEUE_TICK
+func NewQueue(client *redis.Client) *redisQueue {
+	if queueInstance != nil {
+		return queueInstance
+	}
+
+	queueInstance = &redisQueue{
+		client:  client,
+		enabled: fmt.Sprintf("%v", utils.GetEnvOrDefault("DELAY_QUEUE", "0")) == "1",
+		key:     fmt.Sprintf("%v", utils.GetEnvOrDefault("DELAY_QUEUE_KEY", "tasker:reminders:due")),
+		tick:    getTick(),
+	}
+
+	return queueInstance
+}
+
+func (q *redisQueue) Enabled() bool {
+	return q.enabled && q.client != nil
+}
+
+func (q *redisQueue) Tick() time.Duration {
+	return q.tick
+}
+
+func (q *redisQueue) Schedule(reminderId uuid.UUID, at time.Time) error {
+	if !q.Enabled() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return q.client.ZAdd(ctx, q.key, redis.Z{
+		Score:  float64(at.UnixMilli()),
+		Member: reminderId.String(),
+	}).Err()
+}
+
+func (q *redisQueue) Remove(reminderId uuid.UUID) error {
+	if !q.Enabled() {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return q.client.ZRem(ctx, q.key, reminderId.String()).Err()
+}
+
+func (q *redisQueue) Due(currentDateTime time.Time, limit int) ([]uuid.UUID, error) {
+	if !q.Enabled() {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	members, err := q.client.ZRangeByScore(ctx, q.key, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(currentDateTime.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	var reminderIds []uuid.UUID
+	for _, member := range members {
+		reminderId, err := uuid.Parse(member)
+		if err != nil {
+			// Not ours, drop it so it does not come back every tick
+			log.Info("Invalid delay queue member, removing", "member", member)
+			q.client.ZRem(ctx, q.key, member)
+			continue
+		}
+		reminderIds = append(reminderIds, reminderId)
+	}
+
+	return reminderIds, nil
+}
+
+func getTick() time.Duration {
+	tickEnv := fmt.Sprintf("%v", utils.GetEnvOrDefault("DELAY_QUEUE_TICK", "1s"))
+
+	tick, err := time.ParseDuration(tickEnv)
+	if err != nil || tick <= 0 {
+		log.Info("Invalid DELAY_QUEUE_TICK, using default 1s", "value", tickEnv)
+		return time.Second
+	}
+
+	return tick
+}
@@ -0,0 +1,107 @@
+package kafkaconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// Brokers from KAFKA_BROKERS, a comma separated list of host:port
+func Brokers() []string {
+	var brokers []string
+	for _, broker := range strings.Split(GetEnv("KAFKA_BROKERS", "localhost:9092"), ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+
+	return brokers
+}
+
+// Compression codec by name, none when empty
+func Compression(name string) (kafka.Compression, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	}
+
+	return 0, fmt.Errorf("unknown kafka compression %q", name)
+}
+
+// Transport for producers with optional SASL authentication and TLS,
+// enabled with KAFKA_SASL_MECHANISM and KAFKA_TLS
+func Transport() (*kafka.Transport, error) {
+	mechanism, err := SASL()
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Transport{
+		TLS:  TLS(),
+		SASL: mechanism,
+	}, nil
+}
+
+// Dialer for consumers with the same authentication as Transport
+func Dialer() (*kafka.Dialer, error) {
+	mechanism, err := SASL()
+	if err != nil {
+		return nil, err
+	}
+
+	return &kafka.Dialer{
+		Timeout:       10 * time.Second,
+		DualStack:     true,
+		TLS:           TLS(),
+		SASLMechanism: mechanism,
+	}, nil
+}
+
+// TLS config when KAFKA_TLS is 1, nil otherwise
+func TLS() *tls.Config {
+	if GetEnv("KAFKA_TLS", "0") != "1" {
+		return nil
+	}
+
+	return &tls.Config{MinVersion: tls.VersionTLS12}
+}
+
+// SASL mechanism from KAFKA_SASL_MECHANISM,
+// KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD
+func SASL() (sasl.Mechanism, error) {
+	username := GetEnv("KAFKA_SASL_USERNAME", "")
+	password := GetEnv("KAFKA_SASL_PASSWORD", "")
+
+	switch strings.ToLower(GetEnv("KAFKA_SASL_MECHANISM", "")) {
+	case "":
+		return nil, nil
+	case "plain":
+		return plain.Mechanism{Username: username, Password: password}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, username, password)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, username, password)
+	}
+
+	return nil, fmt.Errorf("unknown kafka sasl mechanism %q", GetEnv("KAFKA_SASL_MECHANISM", ""))
+}
+
+// GetEnv reads a string from the environment
+func GetEnv(envName string, defaultValue string) string {
+	return fmt.Sprintf("%v", utils.GetEnvOrDefault(envName, defaultValue))
+}
@@ -0,0 +1,250 @@
+package leader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"github.com/redis/go-redis/v9"
+)
+
+type Elector interface {
+
+	// Start competing for the lease in the background
+	// and keep renewing it while leading
+	Start()
+
+	// Stop competing and release the lease when held
+	// so another replica can take over right away
+	Stop()
+
+	// Check if this replica holds the lease
+	// return the fencing token of the lease, higher for every new leader
+	Leader() (int64, bool)
+}
+
+type redisElector struct {
+	client   *redis.Client
+	db       database.Database
+	enabled  bool
+	id       string
+	key      string
+	fenceKey string
+	lease    time.Duration
+
+	mu     sync.RWMutex
+	leader bool
+	token  int64
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Take the lease when free and hand out the next fencing token,
+// never one at or below the token the database already saw
+// so a restarted or flushed Redis cannot fence out every new leader
+var acquireScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	local token = redis.call("INCR", KEYS[2])
+	local floor = tonumber(ARGV[3])
+	if token <= floor then
+		token = floor + 1
+		redis.call("SET", KEYS[2], token)
+	end
+	return token
+end
+return 0
+`)
+
+// Extend the lease, only while it is still ours
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Give up the lease, only while it is still ours
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+var (
+	electorInstance *redisElector
+	log             = logger.GetLogger()
+)
+
+// Lease on LEADER_KEY lasting LEADER_LEASE, disabled with LEADER_ELECTION=0
+// for single replica setups where this replica always leads.
+// Fencing tokens continue from the dispatcher fence in the database.
+func NewElector(client *redis.Client, db database.Database) *redisElector {
+	if electorInstance != nil {
+		return electorInstance
+	}
+
+	key := fmt.Sprintf("%v", utils.GetEnvOrDefault("LEADER_KEY", "tasker:dispatcher:leader"))
+	electorInstance = &redisElector{
+		client:   client,
+		db:       db,
+		enabled:  fmt.Sprintf("%v", utils.GetEnvOrDefault("LEADER_ELECTION", "1")) == "1",
+		id:       instanceId(),
+		key:      key,
+		fenceKey: key + ":fence",
+		lease:    getLease(),
+	}
+
+	return electorInstance
+}
+
+func (e *redisElector) Start() {
+	if !e.enabled || e.quit != nil {
+		return
+	}
+
+	e.quit = make(chan struct{})
+	e.wg.Add(1)
+	go e.run()
+
+	log.Info("Leader election started", "instance", e.id, "lease", e.lease.String())
+}
+
+func (e *redisElector) Stop() {
+	if e.quit == nil {
+		return
+	}
+
+	close(e.quit)
+	e.wg.Wait()
+	e.quit = nil
+
+	if _, leading := e.Leader(); leading {
+		ctx, cancel := context.WithTimeout(context.Background(), e.lease/3)
+		defer cancel()
+
+		if err := releaseScript.Run(ctx, e.client, []string{e.key}, e.id).Err(); err != nil {
+			log.Info("Failed to release leader lease", "message: ", err)
+		}
+		e.setLeader(false, 0)
+	}
+
+	log.Info("Leader election stopped", "instance", e.id)
+}
+
+func (e *redisElector) Leader() (int64, bool) {
+	if !e.enabled {
+		return 0, true
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.token, e.leader
+}
+
+func (e *redisElector) run() {
+	defer e.wg.Done()
+
+	// Renew well before the lease runs out
+	ticker := time.NewTicker(e.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		e.campaign()
+
+		select {
+		case <-e.quit:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Renew the lease while leading, try to take it otherwise
+func (e *redisElector) campaign() {
+	ctx, cancel := context.WithTimeout(context.Background(), e.lease/3)
+	defer cancel()
+
+	leaseMs := e.lease.Milliseconds()
+
+	if _, leading := e.Leader(); leading {
+		renewed, err := renewScript.Run(ctx, e.client, []string{e.key}, e.id, leaseMs).Int64()
+		if err == nil && renewed == 1 {
+			return
+		}
+
+		// Without a renewal the lease may already belong to someone else
+		e.setLeader(false, 0)
+		log.Info("Lost leader lease", "instance", e.id, "message: ", err)
+		return
+	}
+
+	floor, err := e.fencedToken(ctx)
+	if err != nil {
+		log.Info("Failed to query dispatcher fence", "message: ", err)
+		return
+	}
+
+	token, err := acquireScript.Run(ctx, e.client, []string{e.key, e.fenceKey}, e.id, leaseMs, floor).Int64()
+	if err != nil {
+		log.Info("Failed to acquire leader lease", "message: ", err)
+		return
+	}
+
+	if token > 0 {
+		e.setLeader(true, token)
+		log.Info("Acquired leader lease", "instance", e.id, "token", token)
+	}
+}
+
+// Highest fencing token recorded by a dispatcher, 0 before the first one
+func (e *redisElector) fencedToken(ctx context.Context) (int64, error) {
+	var fences []models.DispatcherFence
+	result := e.db.Gorm().WithContext(ctx).
+		Where("name = ?", models.ReminderDispatcherFence).
+		Limit(1).
+		Find(&fences)
+	if result.Error != nil || len(fences) == 0 {
+		return 0, result.Error
+	}
+
+	return fences[0].Token, nil
+}
+
+func (e *redisElector) setLeader(leader bool, token int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.leader = leader
+	e.token = token
+}
+
+// Host name and process id tell the replicas apart
+func instanceId() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "tasker"
+	}
+
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), utils.GenerateNewUUID().String()[:8])
+}
+
+func getLease() time.Duration {
+	leaseEnv := fmt.Sprintf("%v", utils.GetEnvOrDefault("LEADER_LEASE", "15s"))
+
+	lease, err := time.ParseDuration(leaseEnv)
+	if err != nil || lease < 3*time.Second {
+		log.Info("Invalid LEADER_LEASE, using default 15s", "value", leaseEnv)
+		return 15 * time.Second
+	}
+
+	return lease
+}
@@ -0,0 +1,233 @@
+package message
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/pkg/events"
+)
+
+// Key of the template used for channels without one of their own
+const DefaultKey = "default"
+
+// Templates used when a reminder has none for a channel
+var defaultTemplates = map[string]string{
+	DefaultKey: `{{.Reminder.Name}}: {{.Task.Name}}` +
+		`{{with .Reminder.Description}} - {{.}}{{end}}` +
+		`{{with .Task.DueDate}} (due {{date "2006-01-02 15:04" .}}){{end}}`,
+	"desktop": `{{.Task.Name}}{{with .Reminder.Description}}: {{.}}{{end}}`,
+}
+
+// Template used for digests without one of their own
+const defaultDigestTemplate = `{{len .Items}} reminders` +
+	`{{range .Items}}{{"\n"}}- {{with .Message}}{{.}}{{else}}{{.Reminder}}: {{.Task}}{{end}}{{end}}`
+
+// Task fields available to templates
+type Task struct {
+	Id          uuid.UUID
+	Name        string
+	Description string
+	DueDate     *time.Time
+	Tags        []string
+}
+
+// Reminder fields available to templates
+type Reminder struct {
+	Id          uuid.UUID
+	Name        string
+	Description string
+	Recipient   string
+}
+
+// Data a message template is executed with
+type Data struct {
+	Task         Task
+	Reminder     Reminder
+	OccurrenceId uuid.UUID
+	Occurrence   time.Time
+	Channel      string
+	SnoozeCount  int
+	Escalation   int
+	Level        string
+}
+
+// Data a digest template is executed with,
+// items are the merged events with their own messages rendered
+type Digest struct {
+	Channel   string
+	Recipient string
+	Items     []events.ReminderFired
+}
+
+var (
+	log = logger.GetLogger()
+
+	funcs = template.FuncMap{
+		"date":  func(layout string, t time.Time) string { return t.Format(layout) },
+		"join":  strings.Join,
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}
+)
+
+// NewData collects the template data of a reminder occurrence
+func NewData(reminder models.Reminder, task models.Task, occurrenceId uuid.UUID, occurrence time.Time) Data {
+	return Data{
+		Task: Task{
+			Id:          task.TaskId,
+			Name:        task.Task,
+			Description: task.Description,
+			DueDate:     task.DueDate,
+			Tags:        Tags(task.Tags),
+		},
+		Reminder: Reminder{
+			Id:          reminder.ReminderId,
+			Name:        reminder.Reminder,
+			Description: reminder.Description,
+			Recipient:   reminder.Recipient,
+		},
+		OccurrenceId: occurrenceId,
+		Occurrence:   occurrence,
+	}
+}
+
+// Validate parses every template of a reminder and executes it
+// against sample data, so unknown fields fail on save, not on send.
+// Templates are keyed by channel name or DefaultKey.
+func Validate(templates map[string]string, channels []string) error {
+	dueDate := time.Now()
+	sample := Data{
+		Task:       Task{Name: "task", Description: "description", DueDate: &dueDate, Tags: []string{"tag"}},
+		Reminder:   Reminder{Name: "reminder", Description: "description", Recipient: "recipient"},
+		Occurrence: time.Now(),
+	}
+
+	for key, text := range templates {
+		if key != DefaultKey && !slices.Contains(channels, key) {
+			return fmt.Errorf("template for unknown channel %q", key)
+		}
+
+		tmpl, err := parse(key, text)
+		if err != nil {
+			return err
+		}
+
+		sample.Channel = key
+		if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+			return fmt.Errorf("invalid %s template: %v", key, err)
+		}
+	}
+
+	return nil
+}
+
+// Render the message of a channel, using the reminder template of the channel,
+// its default template or the built in default, in that order
+func Render(templates map[string]string, data Data) string {
+	text, ok := templates[data.Channel]
+	if !ok {
+		text, ok = templates[DefaultKey]
+	}
+	if !ok {
+		text = builtIn(data.Channel)
+	}
+
+	message, err := execute(data.Channel, text, data)
+	if err != nil {
+		log.Info("Failed to render reminder template, using default", "reminder", data.Reminder.Id, "channel", data.Channel, "message: ", err)
+		message, _ = execute(data.Channel, builtIn(data.Channel), data)
+	}
+
+	return message
+}
+
+// ValidateDigest parses a digest template and executes it
+// against sample data, an empty template uses the built in one
+func ValidateDigest(text string) error {
+	if text == "" {
+		return nil
+	}
+
+	tmpl, err := parse("digest", text)
+	if err != nil {
+		return err
+	}
+
+	sample := Digest{
+		Channel:   "channel",
+		Recipient: "recipient",
+		Items: []events.ReminderFired{
+			{Task: "task", Reminder: "reminder", Description: "description", Message: "message", Occurrence: time.Now()},
+		},
+	}
+	if err := tmpl.Execute(&strings.Builder{}, sample); err != nil {
+		return fmt.Errorf("invalid digest template: %v", err)
+	}
+
+	return nil
+}
+
+// RenderDigest renders the message of a digest,
+// falling back to the built in template
+func RenderDigest(text string, digest Digest) string {
+	if text == "" {
+		text = defaultDigestTemplate
+	}
+
+	message, err := execute("digest", text, digest)
+	if err != nil {
+		log.Info("Failed to render digest template, using default", "recipient", digest.Recipient, "channel", digest.Channel, "message: ", err)
+		message, _ = execute("digest", defaultDigestTemplate, digest)
+	}
+
+	return message
+}
+
+// Tags of a task, stored comma separated
+func Tags(tags string) []string {
+	var parsed []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			parsed = append(parsed, tag)
+		}
+	}
+
+	return parsed
+}
+
+func builtIn(channel string) string {
+	if text, ok := defaultTemplates[channel]; ok {
+		return text
+	}
+
+	return defaultTemplates[DefaultKey]
+}
+
+func execute(name string, text string, data any) (string, error) {
+	tmpl, err := parse(name, text)
+	if err != nil {
+		return "", err
+	}
+
+	var message strings.Builder
+	if err := tmpl.Execute(&message, data); err != nil {
+		return "", err
+	}
+
+	return message.String(), nil
+}
+
+func parse(name string, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %v", name, err)
+	}
+
+	return tmpl, nil
+}
@@ -0,0 +1,104 @@
+package message
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevinhartarto/tasker/pkg/events"
+)
+
+func TestRender(t *testing.T) {
+	dueDate := time.Date(2024, 3, 4, 17, 30, 0, 0, time.UTC)
+	data := Data{
+		Task:     Task{Name: "Report", DueDate: &dueDate, Tags: []string{"work", "q1"}},
+		Reminder: Reminder{Name: "Standup", Description: "daily sync", Recipient: "ann"},
+	}
+
+	tests := []struct {
+		name      string
+		templates map[string]string
+		channel   string
+		want      string
+	}{
+		{"built in default", nil, "webhook", "Standup: Report - daily sync (due 2024-03-04 17:30)"},
+		{"built in channel", nil, "desktop", "Report: daily sync"},
+		{"reminder default", map[string]string{DefaultKey: "{{upper .Task.Name}}"}, "webhook", "REPORT"},
+		{"reminder channel", map[string]string{DefaultKey: "default", "webhook": "{{.Channel}} for {{.Reminder.Recipient}}"},
+			"webhook", "webhook for ann"},
+		{"functions", map[string]string{DefaultKey: `{{join .Task.Tags ", "}} {{date "Jan 2" .Task.DueDate}}`}, "stdout",
+			"work, q1 Mar 4"},
+		{"failing template falls back", map[string]string{DefaultKey: "{{.Task.Missing}}"}, "desktop", "Report: daily sync"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			data := data
+			data.Channel = test.channel
+			if got := Render(test.templates, data); got != test.want {
+				t.Errorf("Render() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	channels := []string{"webhook", "stdout"}
+
+	tests := []struct {
+		name      string
+		templates map[string]string
+		wantErr   bool
+	}{
+		{"none", nil, false},
+		{"default and channel", map[string]string{DefaultKey: "{{.Task.Name}}", "webhook": "{{lower .Reminder.Name}}"}, false},
+		{"unknown channel", map[string]string{"sms": "{{.Task.Name}}"}, true},
+		{"syntax error", map[string]string{DefaultKey: "{{.Task.Name"}, true},
+		{"unknown field", map[string]string{DefaultKey: "{{.Task.Owner}}"}, true},
+		{"unknown function", map[string]string{DefaultKey: "{{title .Task.Name}}"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := Validate(test.templates, channels)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Validate() error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	tags := Tags(" work, ,q1,")
+	if len(tags) != 2 || tags[0] != "work" || tags[1] != "q1" {
+		t.Errorf("Tags() = %q, want [work q1]", tags)
+	}
+}
+
+func TestRenderDigest(t *testing.T) {
+	digest := Digest{
+		Channel:   "webhook",
+		Recipient: "ann",
+		Items: []events.ReminderFired{
+			{Reminder: "Standup", Task: "Report", Message: "Standup: Report"},
+			{Reminder: "Review", Task: "Budget"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"built in", "", "2 reminders\n- Standup: Report\n- Review: Budget"},
+		{"custom", "{{.Recipient}} has {{len .Items}} on {{.Channel}}", "ann has 2 on webhook"},
+		{"failing template falls back", "{{.Owner}}", "2 reminders\n- Standup: Report\n- Review: Budget"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := RenderDigest(test.template, digest); got != test.want {
+				t.Errorf("RenderDigest() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Throughput of the reminder dispatcher since tasker started
+type Dispatch struct {
+	Ticks              int64     `json:"ticks"`
+	Batches            int64     `json:"batches"`
+	Dispatched         int64     `json:"dispatched"`
+	Failed             int64     `json:"failed"`
+	LastTickAt         time.Time `json:"last_tick_at"`
+	LastTickDispatched int       `json:"last_tick_dispatched"`
+	LastTickMs         int64     `json:"last_tick_ms"`
+	LastBatchMs        int64     `json:"last_batch_ms"`
+	LastTickPerSecond  float64   `json:"last_tick_per_second"`
+	BusyMs             int64     `json:"busy_ms"`
+	PerSecond          float64   `json:"per_second"`
+}
+
+var (
+	mu       sync.Mutex
+	dispatch Dispatch
+)
+
+// RecordBatch counts a batch of dispatched and failed reminders
+func RecordBatch(dispatched int, failed int, duration time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dispatch.Batches++
+	dispatch.Dispatched += int64(dispatched)
+	dispatch.Failed += int64(failed)
+	dispatch.LastBatchMs = duration.Milliseconds()
+}
+
+// RecordTick counts a scheduler tick with the reminders it dispatched
+func RecordTick(dispatched int, duration time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	dispatch.Ticks++
+	dispatch.LastTickAt = time.Now()
+	dispatch.LastTickDispatched = dispatched
+	dispatch.LastTickMs = duration.Milliseconds()
+	dispatch.LastTickPerSecond = perSecond(int64(dispatched), duration)
+	dispatch.BusyMs += duration.Milliseconds()
+	dispatch.PerSecond = perSecond(dispatch.Dispatched, time.Duration(dispatch.BusyMs)*time.Millisecond)
+}
+
+// DispatchSnapshot returns a copy of the dispatcher metrics
+func DispatchSnapshot() Dispatch {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return dispatch
+}
+
+// Reminders per second of busy time
+func perSecond(count int64, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+
+	return float64(count) / duration.Seconds()
+}
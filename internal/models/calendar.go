@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// What happens to an occurrence landing on an excluded date
+const (
+	CalendarPolicySkip     = "skip"
+	CalendarPolicyNext     = "next"
+	CalendarPolicyPrevious = "previous"
+)
+
+// A named set of dates reminders can be excluded from,
+// e.g. public holidays, with the working week of business day reminders
+type Calendar struct {
+	CalendarId  uuid.UUID `json:"calendar_id" gorm:"type:uuid;primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex"`
+	Description string    `json:"description"`
+	WorkingDays string    `json:"working_days"`
+	WorkStart   string    `json:"work_start"`
+	WorkEnd     string    `json:"work_end"`
+	CreatedAt   time.Time `json:"created"`
+	UpdatedAt   time.Time `json:"updated"`
+}
+
+// An excluded date of a calendar
+type CalendarDate struct {
+	CalendarDateId uuid.UUID `json:"calendar_date_id" gorm:"type:uuid;primaryKey"`
+	CalendarId     uuid.UUID `json:"calendar_id" gorm:"type:uuid;uniqueIndex:idx_calendar_date"`
+	Date           time.Time `json:"date" gorm:"type:date;uniqueIndex:idx_calendar_date"`
+	Name           string    `json:"name"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A window during which reminders for the same recipient and channel
+// are held back and merged into a single digest message.
+// An empty channel or recipient matches any, a zero window turns coalescing off.
+type CoalesceWindow struct {
+	CoalesceWindowId uuid.UUID `json:"coalesce_window_id" gorm:"type:uuid;primaryKey"`
+	Channel          string    `json:"channel" gorm:"uniqueIndex:idx_coalesce_window_subject"`
+	Recipient        string    `json:"recipient" gorm:"uniqueIndex:idx_coalesce_window_subject"`
+	Window           string    `json:"window"`
+	Template         string    `json:"template" gorm:"type:text"`
+	CreatedAt        time.Time `json:"created"`
+	UpdatedAt        time.Time `json:"updated"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// An outbox message given up after too many failed deliveries,
+// kept with its payload so it can be retried
+type DeadLetter struct {
+	DeadLetterId uuid.UUID `json:"dead_letter_id" gorm:"type:uuid;primaryKey"`
+	OutboxId     uuid.UUID `json:"outbox_id" gorm:"type:uuid"`
+	EventId      uuid.UUID `json:"event_id" gorm:"type:uuid;index"`
+	ReminderId   uuid.UUID `json:"reminder_id" gorm:"type:uuid;index"`
+	TaskId       uuid.UUID `json:"task_id" gorm:"type:uuid"`
+	Channel      string    `json:"channel" gorm:"index"`
+	Occurrence   time.Time `json:"occurrence"`
+	Payload      string    `json:"payload" gorm:"type:text"`
+	Attempts     int       `json:"attempts"`
+	Error        string    `json:"error"`
+	FailedAt     time.Time `json:"failed_at" gorm:"index"`
+}
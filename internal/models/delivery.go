@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery statuses, delivered and read are reported back by consumers
+const (
+	DeliverySent      = "sent"
+	DeliveryFailed    = "failed"
+	DeliveryDelivered = "delivered"
+	DeliveryRead      = "read"
+	DeliverySnoozed   = "snoozed"
+)
+
+// A single attempt to deliver a reminder occurrence through a channel
+type ReminderDelivery struct {
+	DeliveryId  uuid.UUID `json:"delivery_id" gorm:"type:uuid;primaryKey"`
+	ReminderId  uuid.UUID `json:"reminder_id" gorm:"type:uuid;index:idx_reminder_delivery_reminder"`
+	OutboxId    uuid.UUID `json:"outbox_id" gorm:"type:uuid"`
+	EventId     uuid.UUID `json:"event_id" gorm:"type:uuid"`
+	Occurrence  time.Time `json:"occurrence"`
+	Channel     string    `json:"channel"`
+	Status      string    `json:"status"`
+	Error       string    `json:"error"`
+	LatencyMs   int64     `json:"latency_ms"`
+	AttemptedAt time.Time `json:"attempted_at" gorm:"index:idx_reminder_delivery_reminder"`
+}
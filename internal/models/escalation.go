@@ -0,0 +1,31 @@
+package models
+
+import (
+	"github.com/google/uuid"
+)
+
+// What an escalation step does with an unacknowledged occurrence
+const (
+	EscalationResend    = "resend"
+	EscalationChannel   = "channel"
+	EscalationRecipient = "recipient"
+)
+
+// Notification levels, alert is the louder one
+const (
+	LevelNotify = "notify"
+	LevelAlert  = "alert"
+)
+
+// A step of the escalation chain of a reminder,
+// taken AfterMinutes after an occurrence fired without being acknowledged
+type EscalationStep struct {
+	EscalationStepId uuid.UUID `json:"escalation_step_id" gorm:"type:uuid;primaryKey"`
+	ReminderId       uuid.UUID `json:"reminder_id" gorm:"type:uuid;index"`
+	Step             int       `json:"step"`
+	AfterMinutes     int       `json:"after_minutes"`
+	Action           string    `json:"action"`
+	Channels         string    `json:"channels"`
+	Recipient        string    `json:"recipient"`
+	Level            string    `json:"level"`
+}
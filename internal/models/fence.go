@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Name of the fence row of the reminder dispatcher
+const ReminderDispatcherFence = "reminder_dispatcher"
+
+// Highest fencing token that wrote as dispatcher,
+// writes holding a lower token come from a deposed leader
+type DispatcherFence struct {
+	Name      string    `json:"name" gorm:"primaryKey"`
+	Token     int64     `json:"token"`
+	UpdatedAt time.Time `json:"updated"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Occurrence statuses
+const (
+	OccurrenceFired        = "fired"
+	OccurrenceSnoozed      = "snoozed"
+	OccurrenceAcknowledged = "acknowledged"
+	OccurrenceDropped      = "dropped"
+	OccurrenceMissed       = "missed"
+)
+
+// A fired occurrence of a reminder,
+// the id is the event id published for the occurrence
+type ReminderOccurrence struct {
+	OccurrenceId    uuid.UUID  `json:"occurrence_id" gorm:"type:uuid;primaryKey"`
+	ReminderId      uuid.UUID  `json:"reminder_id" gorm:"type:uuid;index"`
+	Occurrence      time.Time  `json:"occurrence"`
+	Status          string     `json:"status" gorm:"index:idx_reminder_occurrence_snooze"`
+	FiredAt         time.Time  `json:"fired_at"`
+	SnoozedUntil    *time.Time `json:"snoozed_until" gorm:"index:idx_reminder_occurrence_snooze"`
+	SnoozeCount     int        `json:"snooze_count"`
+	EscalationLevel int        `json:"escalation_level"`
+	AcknowledgedAt  *time.Time `json:"acknowledged_at"`
+	CreatedAt       time.Time  `json:"created"`
+	UpdatedAt       time.Time  `json:"updated"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// A reminder event waiting to be published through a channel,
+// written in the same transaction that advances the reminder
+type Outbox struct {
+	OutboxId      uuid.UUID  `json:"outbox_id" gorm:"type:uuid;primaryKey"`
+	EventId       uuid.UUID  `json:"event_id" gorm:"type:uuid;uniqueIndex:idx_outbox_event_channel"`
+	ReminderId    uuid.UUID  `json:"reminder_id" gorm:"type:uuid;index"`
+	TaskId        uuid.UUID  `json:"task_id" gorm:"type:uuid"`
+	Channel       string     `json:"channel" gorm:"uniqueIndex:idx_outbox_event_channel"`
+	Recipient     string     `json:"recipient" gorm:"not null;default:''"`
+	Occurrence    time.Time  `json:"occurrence"`
+	Payload       string     `json:"payload" gorm:"type:text"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at" gorm:"index"`
+	CreatedAt     time.Time  `json:"created"`
+}
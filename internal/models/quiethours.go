@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Quiet hours scopes
+const (
+	QuietScopeGlobal   = "global"
+	QuietScopeUser     = "user"
+	QuietScopeReminder = "reminder"
+)
+
+// What happens to a reminder firing during quiet hours
+const (
+	QuietPolicyDefer = "defer"
+	QuietPolicyDrop  = "drop"
+)
+
+// A daily window during which reminders are not delivered,
+// subject is the recipient for user windows and the reminder id for reminder windows
+type QuietHours struct {
+	QuietHoursId uuid.UUID `json:"quiet_hours_id" gorm:"type:uuid;primaryKey"`
+	Scope        string    `json:"scope"`
+	Subject      string    `json:"subject"`
+	StartTime    string    `json:"start_time"`
+	EndTime      string    `json:"end_time"`
+	TimeZone     string    `json:"time_zone"`
+	Policy       string    `json:"policy"`
+	CreatedAt    time.Time `json:"created"`
+	UpdatedAt    time.Time `json:"updated"`
+}
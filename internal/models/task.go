@@ -7,27 +7,49 @@ import (
 )
 
 type Task struct {
-	TaskId      uuid.UUID `json:"task_id"`
-	Task        string    `json:"task"`
-	Description string    `json:"description"`
-	Finished    bool      `json:"finished"`
-	CreatedAt   time.Time `json:"created"`
-	UpdatedAt   time.Time `json:"updated"`
+	TaskId      uuid.UUID  `json:"task_id"`
+	Task        string     `json:"task"`
+	Description string     `json:"description"`
+	DueDate     *time.Time `json:"due_date"`
+	Tags        string     `json:"tags"`
+	Finished    bool       `json:"finished"`
+	CreatedAt   time.Time  `json:"created"`
+	UpdatedAt   time.Time  `json:"updated"`
 }
 
+// Catch up policies for occurrences missed while tasker was down
+const (
+	CatchUpAll    = "all"
+	CatchUpLatest = "latest"
+	CatchUpSkip   = "skip"
+)
+
 type Reminder struct {
-	ReminderId        uuid.UUID  `json:"reminder_id"`
-	TaskId            uuid.UUID  `json:"task_id"`
-	Reminder          string     `json:"reminder"`
-	Description       string     `json:"description"`
-	StartTime         time.Time  `json:"start_time"`
-	Frequency         string     `json:"frequency"`
-	RepeatDays        []string   `json:"repeat_days" gorm:"type:text"`
-	RepeatSameday     bool       `json:"repeat_sameday"`
-	RepeatUntil       *time.Time `json:"repeat_until"`
-	Interval          *int       `json:"interval"`
-	IntervalInMinutes *int       `json:"interval_in_minutes"`
-	NextReminder      *time.Time `json:"next_reminder"`
-	CreatedAt         time.Time  `json:"created"`
-	UpdatedAt         time.Time  `json:"updated"`
+	ReminderId        uuid.UUID         `json:"reminder_id"`
+	TaskId            uuid.UUID         `json:"task_id"`
+	Reminder          string            `json:"reminder"`
+	Description       string            `json:"description"`
+	StartTime         time.Time         `json:"start_time"`
+	Frequency         string            `json:"frequency"`
+	RepeatDays        []string          `json:"repeat_days" gorm:"type:text"`
+	RepeatSameday     bool              `json:"repeat_sameday"`
+	RepeatUntil       *time.Time        `json:"repeat_until"`
+	Interval          *int              `json:"interval"`
+	IntervalInMinutes *int              `json:"interval_in_minutes"`
+	NextReminder      *time.Time        `json:"next_reminder" gorm:"index:idx_reminder_due,where:NOT paused"`
+	RRule             string            `json:"rrule"`
+	ExDate            string            `json:"exdate"`
+	RDate             string            `json:"rdate"`
+	TimeZone          string            `json:"time_zone"`
+	Channels          string            `json:"channels"`
+	Recipient         string            `json:"recipient"`
+	CatchUp           string            `json:"catch_up"`
+	Calendar          string            `json:"calendar"`
+	CalendarPolicy    string            `json:"calendar_policy"`
+	BusinessDay       *int              `json:"business_day"`
+	Cron              string            `json:"cron"`
+	Templates         map[string]string `json:"templates" gorm:"serializer:json;type:text"`
+	Paused            bool              `json:"paused" gorm:"default:false"`
+	CreatedAt         time.Time         `json:"created"`
+	UpdatedAt         time.Time         `json:"updated"`
 }
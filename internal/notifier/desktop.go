@@ -0,0 +1,41 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/utils"
+)
+
+type desktopNotifier struct{}
+
+func init() {
+	Register("desktop", newDesktopNotifier)
+}
+
+func newDesktopNotifier() (Notifier, error) {
+	return &desktopNotifier{}, nil
+}
+
+func (dn *desktopNotifier) Name() string {
+	return "desktop"
+}
+
+// Show a desktop notification for every reminder,
+// escalated reminders may ask for an alert instead.
+// Enabled with ENABLE_DESKTOP_NOTIFICATIONS
+func (dn *desktopNotifier) Notify(ctx context.Context, notifications []Notification) error {
+	for _, notification := range notifications {
+		level := notification.Event.Level
+		if level == "" {
+			level = models.LevelNotify
+		}
+		body := notification.Event.Message
+		if body == "" {
+			body = notification.Event.Description
+		}
+		utils.SendDesktopNotification(level, notification.Event.Reminder, body)
+	}
+
+	return nil
+}
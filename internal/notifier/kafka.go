@@ -0,0 +1,104 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/kafkaconfig"
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"github.com/kevinhartarto/tasker/pkg/events"
+	"github.com/segmentio/kafka-go"
+)
+
+type kafkaNotifier struct {
+	writer      *kafka.Writer
+	topic       string
+	digestTopic string
+}
+
+func init() {
+	Register("kafka", newKafkaNotifier)
+}
+
+// Long lived producer configured with the KAFKA_* environment,
+// messages are keyed by task so reminders of a task stay in order.
+// Digests go to KAFKA_DIGEST_TOPIC so reminder consumers never see them.
+func newKafkaNotifier() (Notifier, error) {
+	var acks kafka.RequiredAcks
+	if err := acks.UnmarshalText([]byte(kafkaconfig.GetEnv("KAFKA_ACKS", "all"))); err != nil {
+		return nil, err
+	}
+
+	compression, err := kafkaconfig.Compression(kafkaconfig.GetEnv("KAFKA_COMPRESSION", "none"))
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := kafkaconfig.Transport()
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &kafka.Writer{
+		Addr:            kafka.TCP(kafkaconfig.Brokers()...),
+		Balancer:        &kafka.Hash{},
+		RequiredAcks:    acks,
+		Compression:     compression,
+		Transport:       transport,
+		MaxAttempts:     utils.GetEnvIntOrDefault("KAFKA_MAX_ATTEMPTS", 5),
+		WriteBackoffMin: time.Duration(utils.GetEnvIntOrDefault("KAFKA_BACKOFF_MIN_MS", 100)) * time.Millisecond,
+		WriteBackoffMax: time.Duration(utils.GetEnvIntOrDefault("KAFKA_BACKOFF_MAX_MS", 1000)) * time.Millisecond,
+		BatchSize:       utils.GetEnvIntOrDefault("KAFKA_BATCH_SIZE", 100),
+		BatchTimeout:    time.Duration(utils.GetEnvIntOrDefault("KAFKA_BATCH_TIMEOUT_MS", 100)) * time.Millisecond,
+		WriteTimeout:    10 * time.Second,
+	}
+
+	return &kafkaNotifier{
+		writer:      writer,
+		topic:       kafkaconfig.GetEnv("KAFKA_TOPIC", "tasker_reminder_notify"),
+		digestTopic: kafkaconfig.GetEnv("KAFKA_DIGEST_TOPIC", "tasker_reminder_digest"),
+	}, nil
+}
+
+func (kn *kafkaNotifier) Name() string {
+	return "kafka"
+}
+
+// Send kafka message to mailman
+func (kn *kafkaNotifier) Notify(ctx context.Context, notifications []Notification) error {
+	var reminderToSend []kafka.Message
+	for _, notification := range notifications {
+		event := notification.Event
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		// A digest spans tasks, its recipient keeps it in order with the next one
+		topic, key := kn.topic, event.TaskId.String()
+		if event.Type == events.ReminderDigestType {
+			topic, key = kn.digestTopic, event.Recipient
+		}
+
+		reminderToSend = append(reminderToSend, kafka.Message{
+			Topic: topic,
+			Key:   []byte(key),
+			Value: value,
+			Headers: []kafka.Header{
+				{Key: events.HeaderContentType, Value: []byte(events.ContentTypeJSON)},
+				{Key: events.HeaderCorrelationId, Value: []byte(event.EventId.String())},
+				{Key: events.HeaderSchemaVersion, Value: []byte(strconv.Itoa(event.SchemaVersion))},
+				{Key: events.HeaderEventType, Value: []byte(event.Type)},
+			},
+		})
+	}
+
+	return kn.writer.WriteMessages(ctx, reminderToSend...)
+}
+
+// Flush pending messages and close the producer
+func (kn *kafkaNotifier) Close() error {
+	return kn.writer.Close()
+}
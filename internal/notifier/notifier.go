@@ -0,0 +1,224 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/message"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"github.com/kevinhartarto/tasker/pkg/events"
+)
+
+type Notifier interface {
+
+	// Name of the channel this notifier delivers to
+	Name() string
+
+	// Deliver reminders through the channel
+	// return an error when the channel did not accept them
+	Notify(context.Context, []Notification) error
+}
+
+// A reminder occurrence to deliver,
+// the reminder and task are kept to render the message of every channel
+type Notification struct {
+	Event events.ReminderFired
+
+	reminder models.Reminder
+	task     models.Task
+}
+
+// NewNotification builds the versioned event of a reminder occurrence
+func NewNotification(reminder models.Reminder, task models.Task, occurrence time.Time, channels []string) Notification {
+	occurrenceId := events.OccurrenceId(reminder.ReminderId, occurrence)
+
+	return Notification{
+		Event: events.ReminderFired{
+			SchemaVersion: events.ReminderSchemaVersion,
+			Type:          events.ReminderFiredType,
+			EventId:       occurrenceId,
+			OccurrenceId:  occurrenceId,
+			ReminderId:    reminder.ReminderId,
+			TaskId:        reminder.TaskId,
+			Task:          task.Task,
+			Reminder:      reminder.Reminder,
+			Description:   reminder.Description,
+			Recipient:     reminder.Recipient,
+			Occurrence:    occurrence,
+			Channels:      channels,
+		},
+		reminder: reminder,
+		task:     task,
+	}
+}
+
+// Event to publish on a channel with its message rendered
+// from the reminder template of the channel
+func (n Notification) ChannelEvent(channel string) events.ReminderFired {
+	event := n.Event
+
+	data := message.NewData(n.reminder, n.task, event.OccurrenceId, event.Occurrence)
+	data.Reminder.Recipient = event.Recipient
+	data.Channel = channel
+	data.SnoozeCount = event.SnoozeCount
+	data.Escalation = event.Escalation
+	data.Level = event.Level
+	event.Message = message.Render(n.reminder.Templates, data)
+
+	return event
+}
+
+// NewDigest merges notifications already rendered for a channel
+// into one digest event for their recipient.
+// The digest takes the earliest occurrence and the highest level.
+func NewDigest(channel string, recipient string, notifications []Notification, template string) Notification {
+	var items []events.ReminderFired
+	var eventIds []uuid.UUID
+	for _, notification := range notifications {
+		items = append(items, notification.Event)
+		eventIds = append(eventIds, notification.Event.EventId)
+	}
+
+	digest := events.ReminderFired{
+		SchemaVersion: events.DigestSchemaVersion,
+		Type:          events.ReminderDigestType,
+		EventId:       events.DigestId(eventIds),
+		Reminder:      fmt.Sprintf("%d reminders", len(items)),
+		Recipient:     recipient,
+		Channels:      []string{channel},
+		Items:         items,
+	}
+
+	for _, item := range items {
+		if digest.Occurrence.IsZero() || item.Occurrence.Before(digest.Occurrence) {
+			digest.Occurrence = item.Occurrence
+		}
+		if item.Level == models.LevelAlert {
+			digest.Level = models.LevelAlert
+		}
+		digest.SentAt = item.SentAt
+	}
+
+	digest.Message = message.RenderDigest(template, message.Digest{
+		Channel:   channel,
+		Recipient: recipient,
+		Items:     items,
+	})
+
+	return Notification{Event: digest}
+}
+
+// Builds a notifier from the environment
+type Factory func() (Notifier, error)
+
+var (
+	factories  = map[string]Factory{}
+	configured []string
+	log        = logger.GetLogger()
+)
+
+// Register makes a notifier available under a channel name,
+// implementations register themselves in init
+func Register(name string, factory Factory) {
+	factories[name] = factory
+}
+
+// Registered returns the names of all available channels
+func Registered() []string {
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Configured builds the notifiers listed in NOTIFIERS,
+// channels that fail to start are logged and skipped
+func Configured() []Notifier {
+	var notifiers []Notifier
+
+	for _, name := range ParseChannels(getEnv("NOTIFIERS", "kafka")) {
+		factory, ok := factories[name]
+		if !ok {
+			log.Info("Unknown notifier, skipping", "channel", name)
+			continue
+		}
+
+		notifier, err := factory()
+		if err != nil {
+			log.Info("Failed to start notifier, skipping", "channel", name, "message: ", err)
+			continue
+		}
+
+		notifiers = append(notifiers, notifier)
+		configured = append(configured, name)
+	}
+
+	return notifiers
+}
+
+// CloseAll releases the notifiers holding connections
+func CloseAll(notifiers []Notifier) {
+	for _, notifier := range notifiers {
+		if closer, ok := notifier.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.Info("Failed to close notifier", "channel", notifier.Name(), "message: ", err)
+			}
+		}
+	}
+}
+
+// ParseChannels splits a comma separated channel list
+func ParseChannels(channels string) []string {
+	var names []string
+
+	for _, name := range strings.Split(channels, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// ValidChannels checks every channel of a reminder is a configured notifier,
+// messages for any other channel would never be published
+func ValidChannels(channels string) bool {
+	for _, name := range ParseChannels(channels) {
+		if !slices.Contains(configured, name) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Channels returns the channels a reminder is delivered through,
+// reminders without channels go to every configured notifier
+func Channels(reminder models.Reminder, notifiers []Notifier) []string {
+	if channels := ParseChannels(reminder.Channels); len(channels) > 0 {
+		return channels
+	}
+
+	var channels []string
+	for _, notifier := range notifiers {
+		channels = append(channels, notifier.Name())
+	}
+
+	return channels
+}
+
+func getEnv(envName string, defaultValue string) string {
+	return fmt.Sprintf("%v", utils.GetEnvOrDefault(envName, defaultValue))
+}
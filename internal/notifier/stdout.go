@@ -0,0 +1,39 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+)
+
+type stdoutNotifier struct {
+	encoder *json.Encoder
+}
+
+func init() {
+	Register("stdout", newStdoutNotifier)
+}
+
+func newStdoutNotifier() (Notifier, error) {
+	return &stdoutNotifier{
+		encoder: json.NewEncoder(os.Stdout),
+	}, nil
+}
+
+func (sn *stdoutNotifier) Name() string {
+	return "stdout"
+}
+
+// Print every reminder event as a JSON line,
+// useful when running tasker without any broker
+func (sn *stdoutNotifier) Notify(ctx context.Context, notifications []Notification) error {
+	for _, notification := range notifications {
+		if err := sn.encoder.Encode(notification.Event); err != nil {
+			return err
+		}
+
+		log.Info("Reminder sent", "channel", sn.Name(), "reminder", notification.Event.ReminderId)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kevinhartarto/tasker/pkg/events"
+)
+
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func init() {
+	Register("webhook", newWebhookNotifier)
+}
+
+func newWebhookNotifier() (Notifier, error) {
+	url := getEnv("WEBHOOK_URL", "")
+	if url == "" {
+		return nil, errors.New("WEBHOOK_URL is required")
+	}
+
+	return &webhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (wn *webhookNotifier) Name() string {
+	return "webhook"
+}
+
+// POST every reminder event as JSON to WEBHOOK_URL
+func (wn *webhookNotifier) Notify(ctx context.Context, notifications []Notification) error {
+	for _, notification := range notifications {
+		event := notification.Event
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, wn.url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", events.ContentTypeJSON)
+		request.Header.Set("X-Correlation-Id", event.EventId.String())
+
+		response, err := wn.client.Do(request)
+		if err != nil {
+			return err
+		}
+		response.Body.Close()
+
+		if response.StatusCode >= 300 {
+			return fmt.Errorf("webhook responded with %v", response.Status)
+		}
+	}
+
+	return nil
+}
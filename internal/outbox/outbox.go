@@ -0,0 +1,450 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/coalesce"
+	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/notifier"
+	"github.com/kevinhartarto/tasker/internal/utils"
+	"github.com/kevinhartarto/tasker/pkg/events"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Relay interface {
+
+	// Publish pending outbox messages through their channels
+	// messages are marked sent once the channel accepted them,
+	// messages of a recipient within a coalescing window go out as one digest
+	Publish()
+}
+
+type relay struct {
+	db          database.Database
+	notifiers   map[string]notifier.Notifier
+	batchSize   int
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+}
+
+var (
+	relayInstance *relay
+	log           = logger.GetLogger()
+)
+
+func NewRelay(db database.Database, notifiers []notifier.Notifier) *relay {
+	if relayInstance != nil {
+		return relayInstance
+	}
+
+	notifierByName := map[string]notifier.Notifier{}
+	for _, channel := range notifiers {
+		notifierByName[channel.Name()] = channel
+	}
+
+	relayInstance = &relay{
+		db:          db,
+		notifiers:   notifierByName,
+		batchSize:   utils.GetEnvIntOrDefault("OUTBOX_BATCH_SIZE", 500),
+		maxAttempts: utils.GetEnvIntOrDefault("OUTBOX_MAX_ATTEMPTS", 10),
+		retryBase:   getDuration("OUTBOX_RETRY_BASE", 5*time.Second),
+		retryMax:    getDuration("OUTBOX_RETRY_MAX", 10*time.Minute),
+	}
+
+	return relayInstance
+}
+
+// Enqueue writes one outbox message per channel of every notification,
+// with the message rendered for that channel,
+// must run in the transaction that advances the reminders.
+// Messages already in the outbox for the same occurrence are kept as they are.
+func Enqueue(tx *gorm.DB, notifications []notifier.Notification) error {
+	var messages []models.Outbox
+
+	for _, notification := range notifications {
+		for _, channel := range notification.Event.Channels {
+			payload, err := json.Marshal(notification.ChannelEvent(channel))
+			if err != nil {
+				return err
+			}
+
+			messages = append(messages, models.Outbox{
+				OutboxId:   utils.GenerateNewUUID(),
+				EventId:    notification.Event.EventId,
+				ReminderId: notification.Event.ReminderId,
+				TaskId:     notification.Event.TaskId,
+				Channel:    channel,
+				Recipient:  notification.Event.Recipient,
+				Occurrence: notification.Event.Occurrence,
+				Payload:    string(payload),
+			})
+		}
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&messages).Error
+}
+
+func (r *relay) Publish() {
+	if len(r.notifiers) == 0 {
+		return
+	}
+
+	// Messages for channels not configured here are left alone,
+	// so they cannot fill every batch and hold up the other channels
+	channels := make([]string, 0, len(r.notifiers))
+	for channel := range r.notifiers {
+		channels = append(channels, channel)
+	}
+
+	currentDateTime := time.Now()
+	windows := r.loadWindows()
+	held, err := r.heldRecipients(channels, windows, currentDateTime)
+	if err != nil {
+		log.Info("Failed to query outbox", "message: ", err)
+		return
+	}
+
+	// Recipients held for an open coalescing window stay out of the batch,
+	// so they cannot hold up everyone else until their window closes.
+	// Failed messages wait for their next attempt.
+	query := r.db.Gorm().
+		Where("sent_at IS NULL AND channel IN ?", channels).
+		Where("next_attempt_at IS NULL OR next_attempt_at <= ?", currentDateTime)
+	if len(held) > 0 {
+		query = query.Where("(channel, recipient) NOT IN ?", held)
+	}
+
+	var messages []models.Outbox
+	result := query.Order("created_at").Limit(r.batchSize).Find(&messages)
+
+	if result.Error != nil {
+		log.Info("Failed to query outbox", "message: ", result.Error)
+		return
+	}
+
+	// Publish every channel in one batch
+	byChannel := map[string][]models.Outbox{}
+	for _, message := range messages {
+		byChannel[message.Channel] = append(byChannel[message.Channel], message)
+	}
+
+	for channel, channelMessages := range byChannel {
+		r.publishChannel(channel, channelMessages, windows)
+	}
+}
+
+func (r *relay) publishChannel(channel string, messages []models.Outbox, windows []coalesce.Window) {
+	channelNotifier, ok := r.notifiers[channel]
+	if !ok {
+		log.Info("Notifier not configured, keeping outbox messages", "channel", channel, "count", len(messages))
+		return
+	}
+
+	var notifications []notifier.Notification
+	var published []models.Outbox
+	var outboxIds []uuid.UUID
+	var brokenIds []uuid.UUID
+	sentAt := time.Now()
+
+	// Messages of a recipient are published together, in order of creation
+	var recipients []string
+	byRecipient := map[string][]pending{}
+	for _, message := range messages {
+		var notification notifier.Notification
+		if err := json.Unmarshal([]byte(message.Payload), &notification.Event); err != nil {
+			log.Info("Invalid outbox payload", "outbox", message.OutboxId, "message: ", err)
+			brokenIds = append(brokenIds, message.OutboxId)
+			continue
+		}
+
+		notification.Event.SentAt = sentAt
+		recipient := notification.Event.Recipient
+		if _, ok := byRecipient[recipient]; !ok {
+			recipients = append(recipients, recipient)
+		}
+		byRecipient[recipient] = append(byRecipient[recipient], pending{message, notification})
+	}
+
+	r.markFailed(brokenIds, "invalid payload")
+
+	for _, recipient := range recipients {
+		group := byRecipient[recipient]
+		window, ok := coalesce.Match(windows, channel, recipient)
+		coalescing := ok && window.Duration > 0
+
+		// Held until the window of the oldest message closed
+		if coalescing && sentAt.Before(group[0].message.CreatedAt.Add(window.Duration)) {
+			continue
+		}
+
+		var groupNotifications []notifier.Notification
+		for _, item := range group {
+			groupNotifications = append(groupNotifications, item.notification)
+			published = append(published, item.message)
+			outboxIds = append(outboxIds, item.message.OutboxId)
+		}
+
+		if coalescing && len(group) > 1 {
+			notifications = append(notifications, notifier.NewDigest(channel, recipient, groupNotifications, window.Template))
+		} else {
+			notifications = append(notifications, groupNotifications...)
+		}
+	}
+
+	if len(notifications) == 0 {
+		return
+	}
+
+	err := channelNotifier.Notify(context.Background(), notifications)
+	r.recordDeliveries(channel, published, err, time.Since(sentAt))
+
+	if err != nil {
+		log.Info("Failed to send reminders", "channel", channel, "message: ", err)
+		r.markFailed(outboxIds, err.Error())
+		return
+	}
+
+	result := r.db.Gorm().Model(&models.Outbox{}).
+		Where("outbox_id IN ?", outboxIds).
+		Updates(map[string]interface{}{
+			"sent_at":  sentAt,
+			"attempts": gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		log.Info("Failed to mark outbox messages sent", "channel", channel, "message: ", result.Error)
+	}
+}
+
+// An outbox message waiting to be published
+type pending struct {
+	message      models.Outbox
+	notification notifier.Notification
+}
+
+// Channel and recipient pairs whose oldest pending message
+// is still within its coalescing window
+func (r *relay) heldRecipients(channels []string, windows []coalesce.Window, currentDateTime time.Time) ([][]interface{}, error) {
+	if len(windows) == 0 {
+		return nil, nil
+	}
+
+	var groups []struct {
+		Channel   string
+		Recipient string
+		Oldest    time.Time
+	}
+	result := r.db.Gorm().Model(&models.Outbox{}).
+		Select("channel, recipient, MIN(created_at) AS oldest").
+		Where("sent_at IS NULL AND channel IN ?", channels).
+		Group("channel, recipient").
+		Find(&groups)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	var held [][]interface{}
+	for _, group := range groups {
+		window, ok := coalesce.Match(windows, group.Channel, group.Recipient)
+		if ok && window.Duration > 0 && currentDateTime.Before(group.Oldest.Add(window.Duration)) {
+			held = append(held, []interface{}{group.Channel, group.Recipient})
+		}
+	}
+
+	return held, nil
+}
+
+// Recipient of an outbox payload, empty when it has none
+func payloadRecipient(payload string) string {
+	var event events.ReminderFired
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return ""
+	}
+
+	return event.Recipient
+}
+
+// Coalescing windows of the environment and the database
+func (r *relay) loadWindows() []coalesce.Window {
+	windows := coalesce.FromEnv()
+
+	var coalesceWindows []models.CoalesceWindow
+	if result := r.db.Gorm().Order("created_at").Find(&coalesceWindows); result.Error != nil {
+		log.Info("Failed to query coalescing windows", "message: ", result.Error)
+		return windows
+	}
+
+	for _, record := range coalesceWindows {
+		window, err := coalesce.Parse(record)
+		if err != nil {
+			log.Info("Invalid coalescing window, ignoring", "coalesce_window", record.CoalesceWindowId, "message: ", err)
+			continue
+		}
+		windows = append(windows, window)
+	}
+
+	return windows
+}
+
+func (r *relay) markFailed(outboxIds []uuid.UUID, reason string) {
+	if len(outboxIds) == 0 {
+		return
+	}
+
+	result := r.db.Gorm().Model(&models.Outbox{}).
+		Where("outbox_id IN ?", outboxIds).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": reason,
+			// Exponential backoff, so a short outage does not use up every attempt
+			"next_attempt_at": gorm.Expr("NOW() + make_interval(secs => LEAST(? * POWER(2, attempts), ?))",
+				r.retryBase.Seconds(), r.retryMax.Seconds()),
+		})
+	if result.Error != nil {
+		log.Info("Failed to update outbox messages", "message: ", result.Error)
+		return
+	}
+
+	r.deadLetterExhausted(outboxIds)
+}
+
+// Move messages out of attempts to the dead letters
+func (r *relay) deadLetterExhausted(outboxIds []uuid.UUID) {
+	var messages []models.Outbox
+	result := r.db.Gorm().
+		Where("outbox_id IN ? AND sent_at IS NULL AND attempts >= ?", outboxIds, r.maxAttempts).
+		Find(&messages)
+	if result.Error != nil {
+		log.Info("Failed to query failed outbox messages", "message: ", result.Error)
+		return
+	}
+
+	for _, message := range messages {
+		err := r.db.Gorm().Transaction(func(tx *gorm.DB) error {
+			return DeadLetter(tx, message, message.LastError)
+		})
+		if err != nil {
+			log.Info("Failed to dead letter outbox message", "outbox", message.OutboxId, "message: ", err)
+			continue
+		}
+
+		log.Info("Reminder delivery given up", "event", message.EventId, "channel", message.Channel, "attempts", message.Attempts)
+	}
+}
+
+// DeadLetter moves an outbox message to the dead letters,
+// must run in a transaction
+func DeadLetter(tx *gorm.DB, message models.Outbox, reason string) error {
+	deadLetter := models.DeadLetter{
+		DeadLetterId: utils.GenerateNewUUID(),
+		OutboxId:     message.OutboxId,
+		EventId:      message.EventId,
+		ReminderId:   message.ReminderId,
+		TaskId:       message.TaskId,
+		Channel:      message.Channel,
+		Occurrence:   message.Occurrence,
+		Payload:      message.Payload,
+		Attempts:     message.Attempts,
+		Error:        reason,
+		FailedAt:     time.Now(),
+	}
+
+	if err := tx.Create(&deadLetter).Error; err != nil {
+		return err
+	}
+
+	return tx.Where("outbox_id = ?", message.OutboxId).Delete(&models.Outbox{}).Error
+}
+
+// Redeliver puts dead letters back into the outbox,
+// attempts start over and the event id is kept so consumers can deduplicate.
+// Dead letters whose event is still in the outbox for the channel are kept.
+// Must run in a transaction.
+// return the number of dead letters put back
+func Redeliver(tx *gorm.DB, deadLetters []models.DeadLetter) (int, error) {
+	var deadLetterIds []uuid.UUID
+	for _, deadLetter := range deadLetters {
+		message := models.Outbox{
+			OutboxId:   utils.GenerateNewUUID(),
+			EventId:    deadLetter.EventId,
+			ReminderId: deadLetter.ReminderId,
+			TaskId:     deadLetter.TaskId,
+			Channel:    deadLetter.Channel,
+			Recipient:  payloadRecipient(deadLetter.Payload),
+			Occurrence: deadLetter.Occurrence,
+			Payload:    deadLetter.Payload,
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&message)
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected > 0 {
+			deadLetterIds = append(deadLetterIds, deadLetter.DeadLetterId)
+		}
+	}
+
+	if len(deadLetterIds) == 0 {
+		return 0, nil
+	}
+
+	if err := tx.Where("dead_letter_id IN ?", deadLetterIds).Delete(&models.DeadLetter{}).Error; err != nil {
+		return 0, err
+	}
+
+	return len(deadLetterIds), nil
+}
+
+// Keep a delivery record of every published outbox message
+func (r *relay) recordDeliveries(channel string, messages []models.Outbox, err error, latency time.Duration) {
+	status := models.DeliverySent
+	reason := ""
+	if err != nil {
+		status = models.DeliveryFailed
+		reason = err.Error()
+	}
+
+	attemptedAt := time.Now()
+	var deliveries []models.ReminderDelivery
+	for _, message := range messages {
+		deliveries = append(deliveries, models.ReminderDelivery{
+			DeliveryId:  utils.GenerateNewUUID(),
+			ReminderId:  message.ReminderId,
+			OutboxId:    message.OutboxId,
+			EventId:     message.EventId,
+			Occurrence:  message.Occurrence,
+			Channel:     channel,
+			Status:      status,
+			Error:       reason,
+			LatencyMs:   latency.Milliseconds(),
+			AttemptedAt: attemptedAt,
+		})
+	}
+
+	if result := r.db.Gorm().Create(&deliveries); result.Error != nil {
+		log.Info("Failed to record deliveries", "channel", channel, "message: ", result.Error)
+	}
+}
+
+func getDuration(envName string, defaultValue time.Duration) time.Duration {
+	durationEnv := fmt.Sprintf("%v", utils.GetEnvOrDefault(envName, defaultValue.String()))
+
+	duration, err := time.ParseDuration(durationEnv)
+	if err != nil || duration <= 0 {
+		log.Info("Invalid "+envName+", using default "+defaultValue.String(), "value", durationEnv)
+		return defaultValue
+	}
+
+	return duration
+}
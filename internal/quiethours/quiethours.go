@@ -0,0 +1,168 @@
+package quiethours
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/recurrence"
+	"github.com/kevinhartarto/tasker/internal/utils"
+)
+
+// A parsed quiet hours window
+type Window struct {
+	Scope    string
+	Subject  string
+	Start    time.Duration
+	End      time.Duration
+	Location *time.Location
+	Policy   string
+}
+
+var log = logger.GetLogger()
+
+// Parse validates a quiet hours record and turns it into a window
+func Parse(quietHours models.QuietHours) (Window, error) {
+	window := Window{
+		Scope:   quietHours.Scope,
+		Subject: quietHours.Subject,
+		Policy:  quietHours.Policy,
+	}
+
+	switch window.Scope {
+	case models.QuietScopeGlobal:
+	case models.QuietScopeUser, models.QuietScopeReminder:
+		if window.Subject == "" {
+			return window, fmt.Errorf("subject is required for %v quiet hours", window.Scope)
+		}
+	default:
+		return window, fmt.Errorf("unknown quiet hours scope %q", window.Scope)
+	}
+
+	if window.Policy == "" {
+		window.Policy = models.QuietPolicyDefer
+	}
+	if window.Policy != models.QuietPolicyDefer && window.Policy != models.QuietPolicyDrop {
+		return window, fmt.Errorf("unknown quiet hours policy %q", window.Policy)
+	}
+
+	var err error
+	if window.Start, err = recurrence.ParseClock(quietHours.StartTime); err != nil {
+		return window, err
+	}
+	if window.End, err = recurrence.ParseClock(quietHours.EndTime); err != nil {
+		return window, err
+	}
+	if window.Start == window.End {
+		return window, fmt.Errorf("quiet hours start and end must differ")
+	}
+
+	if window.Location, err = time.LoadLocation(quietHours.TimeZone); err != nil {
+		return window, err
+	}
+
+	return window, nil
+}
+
+// Global window from QUIET_HOURS (e.g. 22:00-07:00),
+// with QUIET_HOURS_POLICY and QUIET_HOURS_TZ
+func FromEnv() []Window {
+	quietHours := fmt.Sprintf("%v", utils.GetEnvOrDefault("QUIET_HOURS", ""))
+	if quietHours == "" {
+		return nil
+	}
+
+	start, end, _ := strings.Cut(quietHours, "-")
+	window, err := Parse(models.QuietHours{
+		Scope:     models.QuietScopeGlobal,
+		StartTime: strings.TrimSpace(start),
+		EndTime:   strings.TrimSpace(end),
+		TimeZone:  fmt.Sprintf("%v", utils.GetEnvOrDefault("QUIET_HOURS_TZ", "UTC")),
+		Policy:    fmt.Sprintf("%v", utils.GetEnvOrDefault("QUIET_HOURS_POLICY", models.QuietPolicyDefer)),
+	})
+	if err != nil {
+		log.Info("Invalid QUIET_HOURS, ignoring", "message: ", err)
+		return nil
+	}
+
+	return []Window{window}
+}
+
+// Applies reports whether a window covers a reminder
+func (w Window) Applies(reminder models.Reminder) bool {
+	switch w.Scope {
+	case models.QuietScopeGlobal:
+		return true
+	case models.QuietScopeUser:
+		return reminder.Recipient != "" && w.Subject == reminder.Recipient
+	case models.QuietScopeReminder:
+		return w.Subject == reminder.ReminderId.String()
+	}
+
+	return false
+}
+
+// Until returns the end of the window when t falls inside it
+func (w Window) Until(t time.Time) (time.Time, bool) {
+	local := t.In(w.Location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, w.Location)
+	clock := time.Duration(local.Hour())*time.Hour +
+		time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+
+	if w.Start < w.End {
+		// Window within a day, e.g. 12:00-13:00
+		if clock >= w.Start && clock < w.End {
+			return atClock(midnight, w.End), true
+		}
+		return time.Time{}, false
+	}
+
+	// Window over midnight, e.g. 22:00-07:00
+	if clock >= w.Start {
+		return atClock(midnight.AddDate(0, 0, 1), w.End), true
+	}
+	if clock < w.End {
+		return atClock(midnight, w.End), true
+	}
+
+	return time.Time{}, false
+}
+
+// Check returns whether a reminder is in quiet hours at t,
+// the policy to apply and when the quiet hours end.
+// Dropping wins over deferring, deferrals last until every window ended.
+func Check(windows []Window, reminder models.Reminder, t time.Time) (bool, string, time.Time) {
+	quiet := false
+	policy := models.QuietPolicyDefer
+	var until time.Time
+
+	for _, window := range windows {
+		if !window.Applies(reminder) {
+			continue
+		}
+
+		end, ok := window.Until(t)
+		if !ok {
+			continue
+		}
+
+		quiet = true
+		if window.Policy == models.QuietPolicyDrop {
+			policy = models.QuietPolicyDrop
+		}
+		if end.After(until) {
+			until = end
+		}
+	}
+
+	return quiet, policy, until
+}
+
+func atClock(midnight time.Time, clock time.Duration) time.Time {
+	hours := int(clock / time.Hour)
+	minutes := int(clock % time.Hour / time.Minute)
+	return time.Date(midnight.Year(), midnight.Month(), midnight.Day(), hours, minutes, 0, 0, midnight.Location())
+}
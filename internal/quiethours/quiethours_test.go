@@ -0,0 +1,139 @@
+package quiethours
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kevinhartarto/tasker/internal/models"
+)
+
+func mustParse(t *testing.T, quietHours models.QuietHours) Window {
+	t.Helper()
+
+	window, err := Parse(quietHours)
+	if err != nil {
+		t.Fatalf("Parse(%+v): %v", quietHours, err)
+	}
+
+	return window
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		quietHours models.QuietHours
+		wantErr    bool
+	}{
+		{"global", models.QuietHours{Scope: models.QuietScopeGlobal, StartTime: "22:00", EndTime: "07:00"}, false},
+		{"user", models.QuietHours{Scope: models.QuietScopeUser, Subject: "ann", StartTime: "22:00", EndTime: "07:00", TimeZone: "Europe/Berlin"}, false},
+		{"drop", models.QuietHours{Scope: models.QuietScopeGlobal, StartTime: "12:00", EndTime: "13:00", Policy: models.QuietPolicyDrop}, false},
+		{"unknown scope", models.QuietHours{Scope: "team", StartTime: "22:00", EndTime: "07:00"}, true},
+		{"missing subject", models.QuietHours{Scope: models.QuietScopeReminder, StartTime: "22:00", EndTime: "07:00"}, true},
+		{"unknown policy", models.QuietHours{Scope: models.QuietScopeGlobal, StartTime: "22:00", EndTime: "07:00", Policy: "delay"}, true},
+		{"invalid start", models.QuietHours{Scope: models.QuietScopeGlobal, StartTime: "10pm", EndTime: "07:00"}, true},
+		{"empty window", models.QuietHours{Scope: models.QuietScopeGlobal, StartTime: "07:00", EndTime: "07:00"}, true},
+		{"unknown time zone", models.QuietHours{Scope: models.QuietScopeGlobal, StartTime: "22:00", EndTime: "07:00", TimeZone: "Mars/Olympus"}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Parse(test.quietHours)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Parse() error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestUntil(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("load location: %v", err)
+	}
+
+	lunch := mustParse(t, models.QuietHours{Scope: models.QuietScopeGlobal, StartTime: "12:00", EndTime: "13:00"})
+	night := mustParse(t, models.QuietHours{Scope: models.QuietScopeGlobal, StartTime: "22:00", EndTime: "07:00", TimeZone: "Europe/Berlin"})
+
+	tests := []struct {
+		name      string
+		window    Window
+		at        time.Time
+		wantQuiet bool
+		wantUntil time.Time
+	}{
+		{"before a day window", lunch, time.Date(2024, 3, 4, 11, 59, 0, 0, time.UTC), false, time.Time{}},
+		{"start of a day window", lunch, time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC), true,
+			time.Date(2024, 3, 4, 13, 0, 0, 0, time.UTC)},
+		{"end of a day window", lunch, time.Date(2024, 3, 4, 13, 0, 0, 0, time.UTC), false, time.Time{}},
+		{"night before midnight", night, time.Date(2024, 3, 4, 23, 30, 0, 0, berlin), true,
+			time.Date(2024, 3, 5, 7, 0, 0, 0, berlin)},
+		{"night after midnight", night, time.Date(2024, 3, 5, 3, 0, 0, 0, berlin), true,
+			time.Date(2024, 3, 5, 7, 0, 0, 0, berlin)},
+		{"night in another zone", night, time.Date(2024, 3, 4, 21, 30, 0, 0, time.UTC), true,
+			time.Date(2024, 3, 5, 7, 0, 0, 0, berlin)},
+		{"daytime", night, time.Date(2024, 3, 4, 12, 0, 0, 0, berlin), false, time.Time{}},
+		{"night over DST", night, time.Date(2024, 3, 30, 23, 0, 0, 0, berlin), true,
+			time.Date(2024, 3, 31, 7, 0, 0, 0, berlin)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			until, quiet := test.window.Until(test.at)
+			if quiet != test.wantQuiet || !until.Equal(test.wantUntil) {
+				t.Errorf("Until(%v) = %v, %v, want %v, %v", test.at, until, quiet, test.wantUntil, test.wantQuiet)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	reminder := models.Reminder{ReminderId: uuid.New(), Recipient: "ann"}
+
+	night := mustParse(t, models.QuietHours{Scope: models.QuietScopeGlobal, StartTime: "22:00", EndTime: "07:00"})
+	late := mustParse(t, models.QuietHours{Scope: models.QuietScopeUser, Subject: "ann", StartTime: "23:00", EndTime: "09:00"})
+	other := mustParse(t, models.QuietHours{Scope: models.QuietScopeUser, Subject: "bob", StartTime: "00:00", EndTime: "23:59", Policy: models.QuietPolicyDrop})
+	drop := mustParse(t, models.QuietHours{Scope: models.QuietScopeReminder, Subject: reminder.ReminderId.String(), StartTime: "06:00", EndTime: "08:00", Policy: models.QuietPolicyDrop})
+
+	at := time.Date(2024, 3, 5, 6, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		windows    []Window
+		wantQuiet  bool
+		wantPolicy string
+		wantUntil  time.Time
+	}{
+		{"no windows", nil, false, models.QuietPolicyDefer, time.Time{}},
+		{"other recipient", []Window{other}, false, models.QuietPolicyDefer, time.Time{}},
+		{"latest end wins", []Window{night, late}, true, models.QuietPolicyDefer,
+			time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{"drop wins", []Window{late, drop}, true, models.QuietPolicyDrop,
+			time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			quiet, policy, until := Check(test.windows, reminder, at)
+			if quiet != test.wantQuiet || policy != test.wantPolicy || !until.Equal(test.wantUntil) {
+				t.Errorf("Check() = %v, %v, %v, want %v, %v, %v",
+					quiet, policy, until, test.wantQuiet, test.wantPolicy, test.wantUntil)
+			}
+		})
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("QUIET_HOURS", "22:00 - 07:00")
+	t.Setenv("QUIET_HOURS_POLICY", models.QuietPolicyDrop)
+
+	windows := FromEnv()
+	if len(windows) != 1 || windows[0].Policy != models.QuietPolicyDrop || windows[0].Start != 22*time.Hour {
+		t.Errorf("FromEnv() = %+v", windows)
+	}
+
+	t.Setenv("QUIET_HOURS", "late")
+	if windows := FromEnv(); windows != nil {
+		t.Errorf("FromEnv() = %+v, want none", windows)
+	}
+}
@@ -0,0 +1,252 @@
+package recurrence
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+)
+
+// Working week used by business day and working hours reminders
+type WorkingHours struct {
+	Days  []time.Weekday
+	Start time.Duration
+	End   time.Duration
+}
+
+// Longest stretch searched for a business day or working hour
+const maxBusinessDays = 3660
+
+// Monday to Friday, 09:00 to 17:00
+var defaultWorkingHours = WorkingHours{
+	Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	Start: 9 * time.Hour,
+	End:   17 * time.Hour,
+}
+
+// ParseWorkingHours reads working days like "mon,tue,wed"
+// and a working day from start to end as HH:MM,
+// empty values keep the Monday to Friday 09:00 to 17:00 default
+func ParseWorkingHours(days string, start string, end string) (WorkingHours, error) {
+	workingHours := defaultWorkingHours
+
+	if days != "" {
+		workingHours.Days = nil
+		for _, day := range strings.Split(days, ",") {
+			weekday, ok := weekdayFromCode(strings.ToLower(strings.TrimSpace(day)))
+			if !ok {
+				return workingHours, fmt.Errorf("unknown working day %q", day)
+			}
+			workingHours.Days = append(workingHours.Days, weekday)
+		}
+	}
+
+	var err error
+	if start != "" {
+		if workingHours.Start, err = ParseClock(start); err != nil {
+			return workingHours, err
+		}
+	}
+	if end != "" {
+		if workingHours.End, err = ParseClock(end); err != nil {
+			return workingHours, err
+		}
+	}
+
+	if workingHours.Start >= workingHours.End {
+		return workingHours, fmt.Errorf("working hours must start before they end")
+	}
+
+	return workingHours, nil
+}
+
+// Working hours of the reminder calendar,
+// the default working week without one
+func workingHoursOf(reminder models.Reminder) WorkingHours {
+	if calendars != nil {
+		if workingHours, ok := calendars.WorkingHours(reminder.Calendar); ok {
+			return workingHours
+		}
+	}
+
+	return defaultWorkingHours
+}
+
+// A working day not excluded by the reminder calendar
+func isBusinessDay(reminder models.Reminder, workingHours WorkingHours, date time.Time) bool {
+	return slices.Contains(workingHours.Days, date.Weekday()) && !excluded(reminder, date)
+}
+
+// Business day reminders fire at the start time of day,
+// either every Interval business days counted from the start time
+// or on the BusinessDay-th business day of every Interval months,
+// counted from the month end when negative
+func nextBusinessDay(reminder models.Reminder, after time.Time) *time.Time {
+	if reminder.Interval == nil || *reminder.Interval <= 0 {
+		return nil
+	}
+
+	workingHours := workingHoursOf(reminder)
+	if reminder.BusinessDay != nil && *reminder.BusinessDay != 0 {
+		return nextBusinessDayOfMonth(reminder, workingHours, after)
+	}
+
+	start := reminder.StartTime
+	interval := *reminder.Interval
+
+	// Jump to the day after, counting the business days skipped
+	first, count := 0, 0
+	if after.After(start) {
+		first = daysBetween(start, after)
+		count = businessDaysBetween(reminder, workingHours, start, after)
+	}
+
+	for i := first; i < first+maxBusinessDays*interval; i++ {
+		candidate := addDays(start, i)
+		if !isBusinessDay(reminder, workingHours, candidate) {
+			continue
+		}
+
+		if count%interval == 0 && candidate.After(after) {
+			return &candidate
+		}
+		count++
+	}
+
+	return nil
+}
+
+// Business days from the date of from up to, not including, the date of to,
+// whole weeks are counted at once and excluded dates taken off after
+func businessDaysBetween(reminder models.Reminder, workingHours WorkingHours, from time.Time, to time.Time) int {
+	days := daysBetween(from, to)
+	if days <= 0 {
+		return 0
+	}
+
+	perWeek := 0
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if slices.Contains(workingHours.Days, weekday) {
+			perWeek++
+		}
+	}
+
+	count := days / 7 * perWeek
+	for i := 0; i < days%7; i++ {
+		if slices.Contains(workingHours.Days, time.Weekday((int(from.Weekday())+i)%7)) {
+			count++
+		}
+	}
+
+	if reminder.Calendar != "" && calendars != nil {
+		for _, date := range calendars.ExcludedBetween(reminder.Calendar, from, to) {
+			if slices.Contains(workingHours.Days, date.Weekday()) {
+				count--
+			}
+		}
+	}
+
+	return count
+}
+
+func nextBusinessDayOfMonth(reminder models.Reminder, workingHours WorkingHours, after time.Time) *time.Time {
+	start := reminder.StartTime
+	interval := *reminder.Interval
+
+	// Jump close to the answer, then walk forward
+	count := monthsBetween(start, after)/interval - 1
+	if count < 0 {
+		count = 0
+	}
+
+	for i := 0; i < maxBusinessDays/28; i++ {
+		first := addMonths(time.Date(start.Year(), start.Month(), 1,
+			start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location()), (count+i)*interval)
+
+		candidate, ok := businessDayOfMonth(reminder, workingHours, first, *reminder.BusinessDay)
+		if ok && !candidate.Before(start) && candidate.After(after) {
+			return &candidate
+		}
+	}
+
+	return nil
+}
+
+// The n-th business day of the month of first,
+// counted from the month end when n is negative
+func businessDayOfMonth(reminder models.Reminder, workingHours WorkingHours, first time.Time, n int) (time.Time, bool) {
+	var days []time.Time
+	for day := 0; day < daysIn(first.Year(), first.Month()); day++ {
+		candidate := addDays(first, day)
+		if isBusinessDay(reminder, workingHours, candidate) {
+			days = append(days, candidate)
+		}
+	}
+
+	index := n - 1
+	if n < 0 {
+		index = len(days) + n
+	}
+	if index < 0 || index >= len(days) {
+		return time.Time{}, false
+	}
+
+	return days[index], true
+}
+
+// Working hours reminders fire every IntervalInMinutes
+// from the start of every business day until, not including, its end
+func nextWorkingHours(reminder models.Reminder, after time.Time) *time.Time {
+	if reminder.IntervalInMinutes == nil || *reminder.IntervalInMinutes <= 0 {
+		return nil
+	}
+
+	workingHours := workingHoursOf(reminder)
+	step := time.Duration(*reminder.IntervalInMinutes) * time.Minute
+	start := reminder.StartTime
+	loc := start.Location()
+
+	from := after
+	if start.After(from) {
+		from = start
+	}
+
+	for i := 0; i < maxBusinessDays; i++ {
+		day := time.Date(from.Year(), from.Month(), from.Day()+i, 0, 0, 0, 0, loc)
+		if !isBusinessDay(reminder, workingHours, day) {
+			continue
+		}
+
+		for slot := workingHours.Start; slot < workingHours.End; slot += step {
+			candidate := wallClock(day.Year(), day.Month(), day.Day(),
+				int(slot/time.Hour), int(slot%time.Hour/time.Minute), 0, 0, loc)
+			if !candidate.Before(start) && candidate.After(after) {
+				return &candidate
+			}
+		}
+	}
+
+	return nil
+}
+
+func weekdayFromCode(code string) (time.Weekday, bool) {
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if weekdayCode(weekday) == code {
+			return weekday, true
+		}
+	}
+
+	return time.Sunday, false
+}
+
+// ParseClock reads a HH:MM time of day
+func ParseClock(value string) (time.Duration, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
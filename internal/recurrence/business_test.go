@@ -0,0 +1,141 @@
+package recurrence
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+)
+
+func TestNextBusinessDay(t *testing.T) {
+	useCalendars(t, fakeCalendars{"holidays": {"2024-03-11"}})
+
+	// Monday 2024-03-04 09:00
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	daily := models.Reminder{StartTime: start, Frequency: FrequencyBusinessDay, Interval: intPtr(1)}
+	everyOther := daily
+	everyOther.Interval = intPtr(2)
+	holidays := daily
+	holidays.Calendar = "holidays"
+	lastOfMonth := models.Reminder{
+		StartTime:   time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		Frequency:   FrequencyBusinessDay,
+		Interval:    intPtr(1),
+		BusinessDay: intPtr(-1),
+	}
+	secondEveryOtherMonth := models.Reminder{
+		StartTime:   time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		Frequency:   FrequencyBusinessDay,
+		Interval:    intPtr(2),
+		BusinessDay: intPtr(2),
+	}
+	noInterval := daily
+	noInterval.Interval = nil
+
+	runNextTests(t, []nextTest{
+		{"first is the start", daily, start.Add(-time.Nanosecond), timePtr(start)},
+		{"over the weekend", daily, time.Date(2024, 3, 8, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC))},
+		{"later the same day", daily, time.Date(2024, 3, 8, 8, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 8, 9, 0, 0, 0, time.UTC))},
+		{"every other business day", everyOther, time.Date(2024, 3, 8, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 12, 9, 0, 0, 0, time.UTC))},
+		{"holiday is no business day", holidays, time.Date(2024, 3, 8, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 12, 9, 0, 0, 0, time.UTC))},
+		{"last business day", lastOfMonth, lastOfMonth.StartTime,
+			timePtr(time.Date(2024, 3, 29, 9, 0, 0, 0, time.UTC))},
+		{"last business day next month", lastOfMonth, time.Date(2024, 3, 29, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC))},
+		{"second business day", secondEveryOtherMonth, secondEveryOtherMonth.StartTime,
+			timePtr(time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC))},
+		{"second business day two months on", secondEveryOtherMonth, time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC))},
+		{"interval is required", noInterval, start, nil},
+	})
+}
+
+func TestNextBusinessDayLongRunning(t *testing.T) {
+	useCalendars(t, fakeCalendars{"holidays": {"2029-12-25", "2030-01-08"}})
+
+	// Monday 2000-01-03 09:00, decades of business days before the answer
+	start := time.Date(2000, 1, 3, 9, 0, 0, 0, time.UTC)
+	everyOther := models.Reminder{StartTime: start, Frequency: FrequencyBusinessDay, Interval: intPtr(2), Calendar: "holidays"}
+	everyThird := everyOther
+	everyThird.Interval = intPtr(3)
+
+	runNextTests(t, []nextTest{
+		{"every other over a holiday", everyOther, time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2030, 1, 9, 9, 0, 0, 0, time.UTC))},
+		{"every other", everyOther, time.Date(2030, 1, 10, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2030, 1, 11, 9, 0, 0, 0, time.UTC))},
+		{"every third", everyThird, time.Date(2030, 1, 9, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2030, 1, 14, 9, 0, 0, 0, time.UTC))},
+	})
+}
+
+func TestNextWorkingHours(t *testing.T) {
+	useCalendars(t, fakeCalendars{"holidays": {"2024-03-11"}})
+
+	// Every 2 hours from 09:00 to 17:00, starting Monday 2024-03-04 10:00
+	reminder := models.Reminder{
+		StartTime:         time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC),
+		Frequency:         FrequencyWorkingHours,
+		IntervalInMinutes: intPtr(120),
+	}
+	holidays := reminder
+	holidays.Calendar = "holidays"
+
+	runNextTests(t, []nextTest{
+		{"no slot before the start", reminder, reminder.StartTime.Add(-time.Hour),
+			timePtr(time.Date(2024, 3, 4, 11, 0, 0, 0, time.UTC))},
+		{"next slot", reminder, time.Date(2024, 3, 5, 11, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 5, 13, 0, 0, 0, time.UTC))},
+		{"end of day is excluded", reminder, time.Date(2024, 3, 5, 15, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC))},
+		{"over the weekend", reminder, time.Date(2024, 3, 8, 15, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC))},
+		{"over a holiday", holidays, time.Date(2024, 3, 8, 15, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 12, 9, 0, 0, 0, time.UTC))},
+	})
+}
+
+func TestParseWorkingHours(t *testing.T) {
+	tests := []struct {
+		name    string
+		days    string
+		start   string
+		end     string
+		want    WorkingHours
+		wantErr bool
+	}{
+		{"default", "", "", "", defaultWorkingHours, false},
+		{"custom", "Sun, mon", "08:30", "12:00", WorkingHours{
+			Days:  []time.Weekday{time.Sunday, time.Monday},
+			Start: 8*time.Hour + 30*time.Minute,
+			End:   12 * time.Hour,
+		}, false},
+		{"unknown day", "mon,xyz", "", "", WorkingHours{}, true},
+		{"invalid clock", "", "9am", "", WorkingHours{}, true},
+		{"start after end", "", "18:00", "", WorkingHours{}, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseWorkingHours(test.days, test.start, test.end)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ParseWorkingHours() = %v, want error", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseWorkingHours(): %v", err)
+			}
+			if !slices.Equal(got.Days, test.want.Days) || got.Start != test.want.Start || got.End != test.want.End {
+				t.Errorf("ParseWorkingHours() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package recurrence
+
+import (
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+)
+
+// Source of the exclusion calendars reminders refer to
+type CalendarSource interface {
+
+	// Check if a calendar with the given name exists
+	Has(name string) bool
+
+	// Check if a date is excluded by the given calendar
+	Excluded(name string, date time.Time) bool
+
+	// Dates excluded by the given calendar
+	// from the date of from up to, not including, the date of to
+	ExcludedBetween(name string, from time.Time, to time.Time) []time.Time
+
+	// Working hours of the given calendar,
+	// the configured default for an empty name
+	WorkingHours(name string) (WorkingHours, bool)
+}
+
+// Longest run of excluded dates looked through
+const maxExcludedDays = 366
+
+var calendars CalendarSource
+
+// UseCalendars sets the exclusion calendars used by the engine
+func UseCalendars(source CalendarSource) {
+	calendars = source
+}
+
+// HasCalendar checks if a reminder calendar exists
+func HasCalendar(name string) bool {
+	return calendars != nil && calendars.Has(name)
+}
+
+// Excluded checks if an occurrence falls on a date
+// excluded by the reminder calendar
+func Excluded(reminder models.Reminder, occurrence time.Time) bool {
+	loc, err := Location(reminder)
+	if err != nil {
+		return false
+	}
+
+	return excluded(reminder, occurrence.In(loc))
+}
+
+// Adjusted moves an occurrence off the excluded dates of the reminder calendar
+// following its calendar policy, false when the occurrence is skipped
+func Adjusted(reminder models.Reminder, occurrence time.Time) (time.Time, bool) {
+	loc, err := Location(reminder)
+	if err != nil {
+		return occurrence, false
+	}
+
+	return applyCalendar(reminder, occurrence.In(loc))
+}
+
+// Move an occurrence off the excluded dates of the reminder calendar,
+// false when the occurrence is skipped
+func applyCalendar(reminder models.Reminder, occurrence time.Time) (time.Time, bool) {
+	if !excluded(reminder, occurrence) {
+		return occurrence, true
+	}
+
+	step := 0
+	switch reminder.CalendarPolicy {
+	case models.CalendarPolicyNext:
+		step = 1
+	case models.CalendarPolicyPrevious:
+		step = -1
+	default:
+		return occurrence, false
+	}
+
+	for i := 1; i <= maxExcludedDays; i++ {
+		shifted := addDays(occurrence, step*i)
+		if !excluded(reminder, shifted) {
+			return shifted, true
+		}
+	}
+
+	return occurrence, false
+}
+
+func excluded(reminder models.Reminder, occurrence time.Time) bool {
+	return reminder.Calendar != "" && calendars != nil && calendars.Excluded(reminder.Calendar, occurrence)
+}
@@ -0,0 +1,63 @@
+package recurrence
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/robfig/cron/v3"
+)
+
+// Standard 5 field expressions, an optional leading seconds field,
+// descriptors like @daily and a CRON_TZ= or TZ= prefix
+var cronParser = cron.NewParser(
+	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// ParseCron parses the cron expression of a reminder,
+// without a time zone prefix it runs in the reminder time zone
+func ParseCron(reminder models.Reminder) (cron.Schedule, error) {
+	expression := strings.TrimSpace(reminder.Cron)
+	if expression == "" {
+		return nil, errors.New("cron expression is required")
+	}
+
+	// Fixed intervals are not anchored to the start time, use "n" instead
+	if strings.HasPrefix(withoutTimeZone(expression), "@every") {
+		return nil, errors.New("@every is not supported, use the \"n\" frequency")
+	}
+
+	return cronParser.Parse(expression)
+}
+
+// The expression after a CRON_TZ= or TZ= prefix
+func withoutTimeZone(expression string) string {
+	if !strings.HasPrefix(expression, "CRON_TZ=") && !strings.HasPrefix(expression, "TZ=") {
+		return expression
+	}
+
+	_, rest, _ := strings.Cut(expression, " ")
+	return strings.TrimSpace(rest)
+}
+
+// Cron reminders fire on the expression from the start time on
+func nextCron(reminder models.Reminder, after time.Time) *time.Time {
+	schedule, err := ParseCron(reminder)
+	if err != nil {
+		return nil
+	}
+
+	from := after
+	if start := reminder.StartTime.Add(-time.Nanosecond); start.After(from) {
+		from = start
+	}
+
+	next := schedule.Next(from)
+	if next.IsZero() {
+		return nil
+	}
+	next = next.In(reminder.StartTime.Location())
+
+	return &next
+}
@@ -0,0 +1,67 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+)
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		cron    string
+		wantErr bool
+	}{
+		{"0 9 * * mon-fri", false},
+		{"30 0 9 * * *", false},
+		{"@daily", false},
+		{"CRON_TZ=Asia/Tokyo 0 9 * * *", false},
+		{"TZ=UTC 0 9 * * *", false},
+		{"", true},
+		{"0 9 * *", true},
+		{"@every 1m", true},
+		{"CRON_TZ=Europe/Berlin @every 1s", true},
+		{"TZ=UTC   @every 1h", true},
+		{"CRON_TZ=Mars/Olympus 0 9 * * *", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.cron, func(t *testing.T) {
+			_, err := ParseCron(models.Reminder{Cron: test.cron})
+			if (err != nil) != test.wantErr {
+				t.Errorf("ParseCron(%q) error = %v, want error %v", test.cron, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestNextCron(t *testing.T) {
+	berlin := location(t, "Europe/Berlin")
+	tokyo := location(t, "Asia/Tokyo")
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	daily := models.Reminder{StartTime: start, Frequency: FrequencyCron, Cron: "0 9 * * *"}
+	zoned := daily
+	zoned.TimeZone = "Europe/Berlin"
+	cronTz := daily
+	cronTz.Cron = "CRON_TZ=Asia/Tokyo 0 9 * * *"
+	weekdays := daily
+	weekdays.Cron = "0 9 * * mon-fri"
+	later := daily
+	later.StartTime = time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+
+	runNextTests(t, []nextTest{
+		{"start time zone", daily, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC))},
+		{"reminder time zone", zoned, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 2, 9, 0, 0, 0, berlin))},
+		{"reminder time zone over DST", zoned, time.Date(2024, 3, 30, 12, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 31, 9, 0, 0, 0, berlin))},
+		{"CRON_TZ wins", cronTz, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 2, 9, 0, 0, 0, tokyo))},
+		{"weekdays only", weekdays, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC))},
+		{"not before the start", later, start,
+			timePtr(time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC))},
+	})
+}
@@ -0,0 +1,277 @@
+package recurrence
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+)
+
+// Frequency codes used by models.Reminder
+const (
+	FrequencyNone     = "n"
+	FrequencyDaily    = "d"
+	FrequencyWeekly   = "w"
+	FrequencyMonthly  = "m"
+	FrequencyYearly   = "y"
+	FrequencySpecific = "s"
+	FrequencyRule     = "r"
+
+	FrequencyBusinessDay  = "b"
+	FrequencyWorkingHours = "h"
+	FrequencyCron         = "c"
+)
+
+// First returns the first occurrence of a reminder,
+// nil when the reminder never fires
+func First(reminder models.Reminder) *time.Time {
+	return Next(reminder, reminder.StartTime.Add(-time.Nanosecond))
+}
+
+// Next returns the first occurrence strictly after the given time,
+// nil when the reminder does not fire again.
+// Occurrences on dates excluded by the reminder calendar are
+// skipped or shifted according to its calendar policy.
+func Next(reminder models.Reminder, after time.Time) *time.Time {
+
+	// Calendar arithmetic happens in the reminder time zone
+	loc, err := Location(reminder)
+	if err != nil {
+		return nil
+	}
+	reminder.StartTime = reminder.StartTime.In(loc)
+	after = after.In(loc)
+
+	// The search gives up once it crossed maxExcludedDays of calendar time,
+	// however many occurrences that took
+	var searchUntil time.Time
+	scheduledAfter := after
+	for {
+		next := nextScheduled(reminder, scheduledAfter)
+		if next == nil || pastRepeatUntil(reminder, *next) {
+			return nil
+		}
+
+		if searchUntil.IsZero() {
+			searchUntil = addDays(*next, maxExcludedDays)
+		} else if next.After(searchUntil) {
+			return nil
+		}
+
+		// Shifting back can land on an occurrence already passed
+		shifted, ok := applyCalendar(reminder, *next)
+		if ok && shifted.After(after) && !pastRepeatUntil(reminder, shifted) {
+			return &shifted
+		}
+
+		scheduledAfter = *next
+	}
+}
+
+// Next occurrence of the schedule itself, calendars aside
+func nextScheduled(reminder models.Reminder, after time.Time) *time.Time {
+	switch reminder.Frequency {
+	case FrequencyNone:
+		return nextMinutes(reminder, after)
+	case FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+		return nextCalendar(reminder, after)
+	case FrequencySpecific:
+		return nextWeekdays(reminder, after)
+	case FrequencyRule:
+		return nextRule(reminder, after)
+	case FrequencyBusinessDay:
+		return nextBusinessDay(reminder, after)
+	case FrequencyWorkingHours:
+		return nextWorkingHours(reminder, after)
+	case FrequencyCron:
+		return nextCron(reminder, after)
+	}
+
+	return nil
+}
+
+// Between returns the occurrences after from and up to to,
+// at most limit of them. A zero to time means no end.
+func Between(reminder models.Reminder, from time.Time, to time.Time, limit int) []time.Time {
+	var occurrences []time.Time
+
+	after := from.Add(-time.Nanosecond)
+	for len(occurrences) < limit {
+		next := Next(reminder, after)
+		if next == nil || (!to.IsZero() && next.After(to)) {
+			break
+		}
+
+		occurrences = append(occurrences, *next)
+		after = *next
+	}
+
+	return occurrences
+}
+
+// Once-off reminders fire at their start time,
+// same day reminders repeat every IntervalInMinutes
+func nextMinutes(reminder models.Reminder, after time.Time) *time.Time {
+	start := reminder.StartTime
+	if start.After(after) {
+		return &start
+	}
+
+	if !reminder.RepeatSameday || reminder.IntervalInMinutes == nil || *reminder.IntervalInMinutes <= 0 {
+		return nil
+	}
+
+	step := time.Duration(*reminder.IntervalInMinutes) * time.Minute
+	count := after.Sub(start)/step + 1
+	next := start.Add(count * step)
+
+	return &next
+}
+
+// Daily, weekly, monthly and yearly reminders repeat every Interval units,
+// always counted from the start time so month ends do not drift
+func nextCalendar(reminder models.Reminder, after time.Time) *time.Time {
+	start := reminder.StartTime
+	if start.After(after) {
+		return &start
+	}
+
+	if reminder.Interval == nil || *reminder.Interval <= 0 {
+		return nil
+	}
+	interval := *reminder.Interval
+
+	// Jump close to the answer, then walk forward
+	count := estimateCount(reminder.Frequency, start, after, interval) - 1
+	if count < 0 {
+		count = 0
+	}
+
+	for {
+		next := occurrence(reminder.Frequency, start, count*interval)
+		if next.After(after) {
+			return &next
+		}
+		count++
+	}
+}
+
+// Specific reminders fire on RepeatDays at the start time of day,
+// every Interval weeks counted from the week of the start time
+func nextWeekdays(reminder models.Reminder, after time.Time) *time.Time {
+	start := reminder.StartTime
+	interval := 1
+	if reminder.Interval != nil && *reminder.Interval > 0 {
+		interval = *reminder.Interval
+	}
+
+	days := make([]string, 0, len(reminder.RepeatDays))
+	for _, day := range reminder.RepeatDays {
+		days = append(days, strings.ToLower(day))
+	}
+	if len(days) == 0 {
+		return nil
+	}
+
+	from := after
+	if start.After(from) {
+		from = start.Add(-time.Nanosecond)
+	}
+
+	startWeek := weekStart(start)
+
+	// One full cycle is enough to find a match
+	for i := 0; i <= 7*(interval+1); i++ {
+		candidate := wallClock(from.Year(), from.Month(), from.Day()+i,
+			start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+		if !candidate.After(from) {
+			continue
+		}
+
+		weeks := daysBetween(startWeek, weekStart(candidate)) / 7
+		if weeks%interval != 0 {
+			continue
+		}
+
+		if slices.Contains(days, weekdayCode(candidate.Weekday())) {
+			return &candidate
+		}
+	}
+
+	return nil
+}
+
+// The n-th occurrence of a calendar reminder,
+// clamped to the last day of the month where needed
+func occurrence(frequency string, start time.Time, units int) time.Time {
+	switch frequency {
+	case FrequencyDaily:
+		return addDays(start, units)
+	case FrequencyWeekly:
+		return addDays(start, 7*units)
+	case FrequencyMonthly:
+		return addMonths(start, units)
+	case FrequencyYearly:
+		return addMonths(start, 12*units)
+	}
+
+	return start
+}
+
+func addMonths(start time.Time, months int) time.Time {
+	year, month, day := start.Date()
+	total := int(month) - 1 + months
+	targetYear := year + total/12
+	targetMonth := time.Month(total%12 + 1)
+
+	if last := daysIn(targetYear, targetMonth); day > last {
+		day = last
+	}
+
+	return wallClock(targetYear, targetMonth, day,
+		start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), start.Location())
+}
+
+func estimateCount(frequency string, start time.Time, after time.Time, interval int) int {
+	switch frequency {
+	case FrequencyDaily:
+		return int(after.Sub(start).Hours()/24) / interval
+	case FrequencyWeekly:
+		return int(after.Sub(start).Hours()/(24*7)) / interval
+	case FrequencyMonthly:
+		return monthsBetween(start, after) / interval
+	case FrequencyYearly:
+		return monthsBetween(start, after) / (12 * interval)
+	}
+
+	return 0
+}
+
+func monthsBetween(from time.Time, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func daysBetween(from time.Time, to time.Time) int {
+	fromDate := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	toDate := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(toDate.Sub(fromDate).Hours() / 24)
+}
+
+// Monday of the week the given time falls in
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return addDays(t, -offset)
+}
+
+func weekdayCode(weekday time.Weekday) string {
+	return strings.ToLower(weekday.String())[0:3]
+}
+
+func pastRepeatUntil(reminder models.Reminder, next time.Time) bool {
+	return reminder.RepeatUntil != nil && !reminder.RepeatUntil.IsZero() && next.After(*reminder.RepeatUntil)
+}
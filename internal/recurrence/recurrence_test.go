@@ -0,0 +1,368 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+)
+
+// Calendars with excluded dates given as 2006-01-02
+type fakeCalendars map[string][]string
+
+func (fc fakeCalendars) Has(name string) bool {
+	_, ok := fc[name]
+	return ok
+}
+
+func (fc fakeCalendars) Excluded(name string, date time.Time) bool {
+	for _, excluded := range fc[name] {
+		if date.Format("2006-01-02") == excluded {
+			return true
+		}
+	}
+	return false
+}
+
+func (fc fakeCalendars) ExcludedBetween(name string, from time.Time, to time.Time) []time.Time {
+	var dates []time.Time
+	for _, excluded := range fc[name] {
+		if excluded >= from.Format("2006-01-02") && excluded < to.Format("2006-01-02") {
+			date, _ := time.Parse("2006-01-02", excluded)
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+func (fc fakeCalendars) WorkingHours(name string) (WorkingHours, bool) {
+	return defaultWorkingHours, true
+}
+
+func useCalendars(t *testing.T, source CalendarSource) {
+	t.Helper()
+	UseCalendars(source)
+	t.Cleanup(func() { UseCalendars(nil) })
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestNextSkipsExcludedDayOfMinuteReminder(t *testing.T) {
+	useCalendars(t, fakeCalendars{"holidays": {"2024-03-09"}})
+
+	reminder := models.Reminder{
+		StartTime:         time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC),
+		Frequency:         FrequencyNone,
+		RepeatSameday:     true,
+		IntervalInMinutes: intPtr(1),
+		Calendar:          "holidays",
+		CalendarPolicy:    models.CalendarPolicySkip,
+	}
+
+	tests := []struct {
+		after time.Time
+		want  time.Time
+	}{
+		{time.Date(2024, 3, 8, 23, 58, 0, 0, time.UTC), time.Date(2024, 3, 8, 23, 59, 0, 0, time.UTC)},
+		{time.Date(2024, 3, 8, 23, 59, 0, 0, time.UTC), time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		next := Next(reminder, test.after)
+		if next == nil {
+			t.Fatalf("Next(%v) = nil, want %v", test.after, test.want)
+		}
+		if !next.Equal(test.want) {
+			t.Errorf("Next(%v) = %v, want %v", test.after, *next, test.want)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
+type nextTest struct {
+	name     string
+	reminder models.Reminder
+	after    time.Time
+	want     *time.Time
+}
+
+func runNextTests(t *testing.T, tests []nextTest) {
+	t.Helper()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := Next(test.reminder, test.after)
+			switch {
+			case test.want == nil && next != nil:
+				t.Errorf("Next(%v) = %v, want nil", test.after, *next)
+			case test.want != nil && next == nil:
+				t.Errorf("Next(%v) = nil, want %v", test.after, *test.want)
+			case test.want != nil && !next.Equal(*test.want):
+				t.Errorf("Next(%v) = %v, want %v", test.after, *next, *test.want)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	monthly := models.Reminder{
+		StartTime: time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+		Frequency: FrequencyMonthly,
+		Interval:  intPtr(1),
+	}
+	everyOtherMonth := monthly
+	everyOtherMonth.Interval = intPtr(2)
+	yearly := models.Reminder{
+		StartTime: time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+		Frequency: FrequencyYearly,
+		Interval:  intPtr(1),
+	}
+	weekdays := models.Reminder{
+		StartTime:  time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		Frequency:  FrequencySpecific,
+		RepeatDays: []string{"Mon", "wed"},
+		Interval:   intPtr(2),
+	}
+	minutes := models.Reminder{
+		StartTime:         time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		Frequency:         FrequencyNone,
+		RepeatSameday:     true,
+		IntervalInMinutes: intPtr(45),
+	}
+	once := models.Reminder{
+		StartTime: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		Frequency: FrequencyNone,
+	}
+	until := monthly
+	until.RepeatUntil = timePtr(time.Date(2024, 3, 30, 0, 0, 0, 0, time.UTC))
+
+	runNextTests(t, []nextTest{
+		{"monthly clamps to leap day", monthly, monthly.StartTime,
+			timePtr(time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC))},
+		{"monthly returns to month end", monthly, time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC))},
+		{"monthly clamps to 30 days", monthly, time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 4, 30, 9, 0, 0, 0, time.UTC))},
+		{"every other month", everyOtherMonth, everyOtherMonth.StartTime,
+			timePtr(time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC))},
+		{"every other month far ahead", everyOtherMonth, time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2025, 7, 31, 9, 0, 0, 0, time.UTC))},
+		{"yearly leap day in common year", yearly, yearly.StartTime,
+			timePtr(time.Date(2025, 2, 28, 9, 0, 0, 0, time.UTC))},
+		{"yearly leap day in leap year", yearly, time.Date(2027, 3, 1, 0, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2028, 2, 29, 9, 0, 0, 0, time.UTC))},
+		{"first occurrence is the start", monthly, monthly.StartTime.Add(-time.Nanosecond),
+			timePtr(monthly.StartTime)},
+		{"specific days", weekdays, weekdays.StartTime,
+			timePtr(time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC))},
+		{"specific days skip a week", weekdays, time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC))},
+		{"same day minutes", minutes, time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 4, 10, 30, 0, 0, time.UTC))},
+		{"once before start", once, once.StartTime.Add(-time.Minute), timePtr(once.StartTime)},
+		{"once after start", once, once.StartTime, nil},
+		{"repeat until ends", until, time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC), nil},
+	})
+}
+
+func location(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func TestNextTimeZone(t *testing.T) {
+	berlin := location(t, "Europe/Berlin")
+	newYork := location(t, "America/New_York")
+
+	daily := models.Reminder{
+		StartTime: time.Date(2024, 3, 30, 9, 0, 0, 0, berlin),
+		Frequency: FrequencyDaily,
+		Interval:  intPtr(1),
+	}
+	gap := models.Reminder{
+		StartTime: time.Date(2024, 3, 30, 2, 30, 0, 0, berlin),
+		Frequency: FrequencyDaily,
+		Interval:  intPtr(1),
+	}
+	overlap := models.Reminder{
+		StartTime: time.Date(2024, 10, 26, 2, 30, 0, 0, berlin),
+		Frequency: FrequencyDaily,
+		Interval:  intPtr(1),
+	}
+	zoned := models.Reminder{
+		StartTime: time.Date(2024, 3, 1, 14, 0, 0, 0, time.UTC),
+		Frequency: FrequencyDaily,
+		Interval:  intPtr(1),
+		TimeZone:  "America/New_York",
+	}
+	rule := models.Reminder{
+		StartTime: time.Date(2024, 3, 30, 9, 0, 0, 0, berlin),
+		Frequency: FrequencyRule,
+		RRule:     "FREQ=DAILY",
+	}
+	unknown := models.Reminder{
+		StartTime: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		Frequency: FrequencyNone,
+		TimeZone:  "Mars/Olympus",
+	}
+
+	runNextTests(t, []nextTest{
+		{"daily keeps wall clock over DST", daily, daily.StartTime,
+			timePtr(time.Date(2024, 3, 31, 9, 0, 0, 0, berlin))},
+		{"DST gap moves forward", gap, gap.StartTime,
+			timePtr(time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC))},
+		{"after DST gap", gap, time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 4, 1, 0, 30, 0, 0, time.UTC))},
+		{"DST overlap takes the first instant", overlap, overlap.StartTime,
+			timePtr(time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC))},
+		{"reminder time zone", zoned, time.Date(2024, 3, 9, 15, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 10, 9, 0, 0, 0, newYork))},
+		{"rule keeps wall clock over DST", rule, rule.StartTime,
+			timePtr(time.Date(2024, 3, 31, 9, 0, 0, 0, berlin))},
+		{"unknown time zone", unknown, unknown.StartTime.Add(-time.Minute), nil},
+	})
+}
+
+func TestBetween(t *testing.T) {
+	reminder := models.Reminder{
+		StartTime: time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+		Frequency: FrequencyMonthly,
+		Interval:  intPtr(1),
+	}
+
+	tests := []struct {
+		name  string
+		from  time.Time
+		to    time.Time
+		limit int
+		want  []time.Time
+	}{
+		{"from is inclusive", reminder.StartTime, time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC), 10, []time.Time{
+			time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC),
+		}},
+		{"limit", reminder.StartTime, time.Time{}, 2, []time.Time{
+			time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+		}},
+		{"empty range", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC), 10, nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := Between(reminder, test.from, test.to, test.limit)
+			if len(got) != len(test.want) {
+				t.Fatalf("Between() = %v, want %v", got, test.want)
+			}
+			for i := range got {
+				if !got[i].Equal(test.want[i]) {
+					t.Errorf("Between()[%d] = %v, want %v", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNextCalendarPolicy(t *testing.T) {
+	useCalendars(t, fakeCalendars{"holidays": {"2024-03-11", "2024-03-12"}})
+
+	// Mondays 09:00, Monday 2024-03-11 and the Tuesday after are excluded
+	weekly := models.Reminder{
+		StartTime: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		Frequency: FrequencyWeekly,
+		Interval:  intPtr(1),
+		Calendar:  "holidays",
+	}
+	skip := weekly
+	skip.CalendarPolicy = models.CalendarPolicySkip
+	next := weekly
+	next.CalendarPolicy = models.CalendarPolicyNext
+	previous := weekly
+	previous.CalendarPolicy = models.CalendarPolicyPrevious
+	unknown := weekly
+	unknown.Calendar = "unknown"
+
+	after := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	runNextTests(t, []nextTest{
+		{"default skips", weekly, after, timePtr(time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC))},
+		{"skip", skip, after, timePtr(time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC))},
+		{"next working date", next, after, timePtr(time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC))},
+		{"previous date", previous, after, timePtr(time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC))},
+		{"previous not before after", previous, time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC),
+			timePtr(time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC))},
+		{"unknown calendar excludes nothing", unknown, after, timePtr(time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC))},
+	})
+
+	if !Excluded(weekly, time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Excluded(2024-03-11) = false, want true")
+	}
+	if Excluded(weekly, time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("Excluded(2024-03-13) = true, want false")
+	}
+}
+
+func TestNextGivesUpOnExcludedYear(t *testing.T) {
+	var dates []string
+	for day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC); day.Year() < 2026; day = day.AddDate(0, 0, 1) {
+		dates = append(dates, day.Format("2006-01-02"))
+	}
+	useCalendars(t, fakeCalendars{"closed": dates})
+
+	reminder := models.Reminder{
+		StartTime: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+		Frequency: FrequencyDaily,
+		Interval:  intPtr(1),
+		Calendar:  "closed",
+	}
+
+	if next := Next(reminder, reminder.StartTime); next != nil {
+		t.Errorf("Next() = %v, want nil", *next)
+	}
+}
+
+func TestAdjusted(t *testing.T) {
+	useCalendars(t, fakeCalendars{"holidays": {"2024-03-11", "2024-03-12"}})
+
+	reminder := models.Reminder{
+		StartTime: time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC),
+		Frequency: FrequencyWeekly,
+		Interval:  intPtr(1),
+		Calendar:  "holidays",
+	}
+	next := reminder
+	next.CalendarPolicy = models.CalendarPolicyNext
+	previous := reminder
+	previous.CalendarPolicy = models.CalendarPolicyPrevious
+
+	excluded := time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		reminder models.Reminder
+		at       time.Time
+		want     time.Time
+		wantOk   bool
+	}{
+		{"not excluded", reminder, time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC), time.Date(2024, 3, 18, 9, 0, 0, 0, time.UTC), true},
+		{"skip", reminder, excluded, excluded, false},
+		{"next working date", next, excluded, time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC), true},
+		{"previous date", previous, excluded, time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := Adjusted(test.reminder, test.at)
+			if ok != test.wantOk || !got.Equal(test.want) {
+				t.Errorf("Adjusted(%v) = %v, %v, want %v, %v", test.at, got, ok, test.want, test.wantOk)
+			}
+		})
+	}
+}
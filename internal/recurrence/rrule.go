@@ -0,0 +1,108 @@
+package recurrence
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/teambition/rrule-go"
+)
+
+// ParseRule builds the RFC 5545 recurrence set of a reminder,
+// DTSTART is always taken from the reminder start time
+func ParseRule(reminder models.Reminder) (*rrule.Set, error) {
+	rule := strings.TrimSpace(reminder.RRule)
+	rule = strings.TrimPrefix(rule, "RRULE:")
+	if rule == "" {
+		return nil, errors.New("rrule is required")
+	}
+
+	start := reminder.StartTime
+	options, err := rrule.StrToROptionInLocation(rule, start.Location())
+	if err != nil {
+		return nil, err
+	}
+	options.Dtstart = start
+
+	rRule, err := rrule.NewRRule(*options)
+	if err != nil {
+		return nil, err
+	}
+
+	set := &rrule.Set{}
+	set.DTStart(start)
+	set.RRule(rRule)
+
+	rDates, err := parseDates(reminder.RDate, start.Location())
+	if err != nil {
+		return nil, err
+	}
+	set.SetRDates(rDates)
+
+	exDates, err := parseDates(reminder.ExDate, start.Location())
+	if err != nil {
+		return nil, err
+	}
+	set.SetExDates(exDates)
+
+	return set, nil
+}
+
+// Reminders with an RRULE are expanded by the rule set
+func nextRule(reminder models.Reminder, after time.Time) *time.Time {
+	set, err := ParseRule(reminder)
+	if err != nil {
+		return nil
+	}
+
+	next := set.After(after, false)
+	if next.IsZero() {
+		return nil
+	}
+
+	return &next
+}
+
+// Parse EXDATE or RDATE values, one property per line,
+// with or without the property name and parameters.
+// Dates without a zone are read in the TZID parameter when given.
+func parseDates(value string, loc *time.Location) ([]time.Time, error) {
+	var dates []time.Time
+
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		lineLoc := loc
+		if i := strings.LastIndex(line, ":"); i >= 0 {
+			var err error
+			if lineLoc, err = dateLocation(line[:i], loc); err != nil {
+				return nil, err
+			}
+			line = line[i+1:]
+		}
+
+		lineDates, err := rrule.StrToDatesInLoc(line, lineLoc)
+		if err != nil {
+			return nil, err
+		}
+		dates = append(dates, lineDates...)
+	}
+
+	return dates, nil
+}
+
+// Location of the TZID parameter of a property like EXDATE;TZID=Europe/Berlin
+func dateLocation(property string, loc *time.Location) (*time.Location, error) {
+	for _, parameter := range strings.Split(property, ";")[1:] {
+		name, zone, _ := strings.Cut(parameter, "=")
+		if strings.EqualFold(name, "TZID") {
+			return time.LoadLocation(strings.Trim(zone, `"`))
+		}
+	}
+
+	return loc, nil
+}
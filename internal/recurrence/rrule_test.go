@@ -0,0 +1,42 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+)
+
+func TestNextRule(t *testing.T) {
+	// Monday 2024-03-04 09:00
+	start := time.Date(2024, 3, 4, 9, 0, 0, 0, time.UTC)
+	weekly := models.Reminder{StartTime: start, Frequency: FrequencyRule, RRule: "RRULE:FREQ=WEEKLY;BYDAY=MO,WE"}
+	exDate := weekly
+	exDate.ExDate = "EXDATE:20240306T090000Z"
+	exDateZoned := weekly
+	exDateZoned.ExDate = "EXDATE;TZID=Europe/Berlin:20240306T100000"
+	exDates := weekly
+	exDates.ExDate = "EXDATE:20240306T090000Z\nEXDATE;VALUE=DATE-TIME:20240311T090000Z"
+	rDate := weekly
+	rDate.RDate = "20240305T120000Z"
+	monthEnd := models.Reminder{
+		StartTime: time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+		Frequency: FrequencyRule,
+		RRule:     "FREQ=MONTHLY;BYMONTHDAY=-1",
+	}
+	count := models.Reminder{StartTime: start, Frequency: FrequencyRule, RRule: "FREQ=DAILY;COUNT=2"}
+	invalid := models.Reminder{StartTime: start, Frequency: FrequencyRule, RRule: "FREQ=SOMETIMES"}
+
+	runNextTests(t, []nextTest{
+		{"weekly by day", weekly, start, timePtr(time.Date(2024, 3, 6, 9, 0, 0, 0, time.UTC))},
+		{"exdate", exDate, start, timePtr(time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC))},
+		{"exdate with time zone", exDateZoned, start, timePtr(time.Date(2024, 3, 11, 9, 0, 0, 0, time.UTC))},
+		{"exdate lines", exDates, start, timePtr(time.Date(2024, 3, 13, 9, 0, 0, 0, time.UTC))},
+		{"rdate", rDate, start, timePtr(time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC))},
+		{"last day of month", monthEnd, monthEnd.StartTime,
+			timePtr(time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC))},
+		{"count", count, start, timePtr(time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC))},
+		{"count ends", count, time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC), nil},
+		{"invalid rule", invalid, start, nil},
+	})
+}
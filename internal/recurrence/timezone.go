@@ -0,0 +1,55 @@
+package recurrence
+
+import (
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/models"
+)
+
+// Location returns the IANA time zone a reminder is expanded in,
+// reminders without a time zone keep the zone of their start time
+func Location(reminder models.Reminder) (*time.Location, error) {
+	if reminder.TimeZone == "" {
+		return reminder.StartTime.Location(), nil
+	}
+
+	return time.LoadLocation(reminder.TimeZone)
+}
+
+// Build a wall clock time in the given zone.
+// A time skipped by a DST gap is moved forward by the length of the gap,
+// a time repeated by a DST overlap resolves to its first occurrence.
+func wallClock(year int, month time.Month, day, hour, min, sec, nsec int, loc *time.Location) time.Time {
+	t := time.Date(year, month, day, hour, min, sec, nsec, loc)
+
+	_, offsetBefore := t.Add(-24 * time.Hour).Zone()
+	_, offsetAfter := t.Add(24 * time.Hour).Zone()
+	if offsetBefore == offsetAfter {
+		return t
+	}
+
+	wall := time.Date(year, month, day, hour, min, sec, nsec, time.UTC)
+	if !sameWallClock(t, wall) {
+		// Gap, use the offset in effect before the transition
+		return wall.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
+	}
+
+	// Overlap, prefer the earlier of the two instants
+	earlier := wall.Add(-time.Duration(offsetBefore) * time.Second).In(loc)
+	if earlier.Before(t) && sameWallClock(earlier, wall) {
+		return earlier
+	}
+
+	return t
+}
+
+// Move a wall clock time by whole days, keeping the time of day
+func addDays(t time.Time, days int) time.Time {
+	return wallClock(t.Year(), t.Month(), t.Day()+days,
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+func sameWallClock(t time.Time, wall time.Time) bool {
+	return t.Year() == wall.Year() && t.YearDay() == wall.YearDay() &&
+		t.Hour() == wall.Hour() && t.Minute() == wall.Minute() && t.Second() == wall.Second()
+}
@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kevinhartarto/tasker/internal/logger"
+	"github.com/kevinhartarto/tasker/internal/utils"
+)
+
+type Scheduler interface {
+
+	// Start polling for due reminders
+	// every tick runs in the background until Stop is called
+	Start()
+
+	// Stop polling for due reminders
+	// waits for the running tick to finish
+	Stop()
+}
+
+// Anything able to dispatch due reminders,
+// implemented by the reminder controller
+type reminderSender interface {
+	CatchUpReminders(time.Duration, int64)
+	SendReminder(int64)
+	SendQueuedReminders(int64)
+}
+
+// Anything reporting reminders due between ticks,
+// implemented by the delay queue
+type delayQueue interface {
+	Enabled() bool
+	Tick() time.Duration
+}
+
+// Anything telling whether this replica may dispatch,
+// implemented by the leader elector
+type leaderElector interface {
+	Leader() (int64, bool)
+}
+
+type scheduler struct {
+	reminder reminderSender
+	elector  leaderElector
+	queue    delayQueue
+	tick     time.Duration
+	quit     chan struct{}
+	wg       sync.WaitGroup
+}
+
+var (
+	schedulerInstance *scheduler
+	log               = logger.GetLogger()
+)
+
+func NewScheduler(reminder reminderSender, elector leaderElector, queue delayQueue) *scheduler {
+	if schedulerInstance != nil {
+		return schedulerInstance
+	}
+
+	schedulerInstance = &scheduler{
+		reminder: reminder,
+		elector:  elector,
+		queue:    queue,
+		tick:     getTick(),
+	}
+
+	return schedulerInstance
+}
+
+func (s *scheduler) Start() {
+	if s.quit != nil {
+		return
+	}
+
+	s.quit = make(chan struct{})
+	s.wg.Add(1)
+	go s.run()
+
+	log.Info("Reminder scheduler started", "tick", s.tick.String())
+}
+
+func (s *scheduler) Stop() {
+	if s.quit == nil {
+		return
+	}
+
+	close(s.quit)
+	s.wg.Wait()
+	s.quit = nil
+
+	log.Info("Reminder scheduler stopped")
+}
+
+func (s *scheduler) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.tick)
+	defer ticker.Stop()
+
+	// The delay queue is checked between the database polls
+	var queued <-chan time.Time
+	if s.queue.Enabled() {
+		queueTicker := time.NewTicker(s.queue.Tick())
+		defer queueTicker.Stop()
+		queued = queueTicker.C
+	}
+
+	leading := false
+	for {
+		token, leader := s.elector.Leader()
+		switch {
+		case leader && !leading:
+			// Reminders missed while no replica was leading go first,
+			// anything due within the last tick counts as on time
+			log.Info("Dispatching reminders as leader", "token", token)
+			s.reminder.CatchUpReminders(s.tick, token)
+		case leader:
+			s.reminder.SendReminder(token)
+		case leading:
+			log.Info("No longer leader, dispatching stopped")
+		}
+		leading = leader
+
+		if !s.wait(ticker.C, queued, leading) {
+			return
+		}
+	}
+}
+
+// Wait for the next tick, dispatching what the delay queue reports due
+// in the meantime while leading
+// return false once the scheduler is stopped
+func (s *scheduler) wait(tick <-chan time.Time, queued <-chan time.Time, leading bool) bool {
+	for {
+		select {
+		case <-s.quit:
+			return false
+		case <-tick:
+			return true
+		case <-queued:
+			if !leading {
+				continue
+			}
+
+			// Leadership may have been lost since the last tick
+			if token, leader := s.elector.Leader(); leader {
+				s.reminder.SendQueuedReminders(token)
+			}
+		}
+	}
+}
+
+func getTick() time.Duration {
+	tickEnv := fmt.Sprintf("%v", utils.GetEnvOrDefault("REMINDER_TICK", "1m"))
+
+	tick, err := time.ParseDuration(tickEnv)
+	if err != nil || tick <= 0 {
+		log.Info("Invalid REMINDER_TICK, using default 1m", "value", tickEnv)
+		return time.Minute
+	}
+
+	return tick
+}
@@ -11,6 +11,8 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/monitor"
 	"github.com/kevinhartarto/tasker/internal/controllers"
 	"github.com/kevinhartarto/tasker/internal/database"
+	"github.com/kevinhartarto/tasker/internal/delayqueue"
+	"github.com/kevinhartarto/tasker/internal/metrics"
 	"github.com/kevinhartarto/tasker/internal/utils"
 	"github.com/redis/go-redis/v9"
 )
@@ -49,9 +51,13 @@ func TaskerHandler(database database.Database, redis redis.Client) *fiber.App {
 	v1.Get("/metrics", monitor.New(monitor.Config{
 		Title: "Tasker Metrics Page",
 	}))
+	v1.Get("/metrics/dispatcher", func(c *fiber.Ctx) error {
+		return c.Status(fiber.StatusOK).JSON(metrics.DispatchSnapshot())
+	})
 
 	// Tasker APIs
-	list := controllers.NewTaskController(database)
+	queue := delayqueue.NewQueue(&redis)
+	list := controllers.NewTaskController(database, queue)
 	listAPI := v1.Group("/list")
 
 	listAPI.Get("/ping", func(c *fiber.Ctx) error {
@@ -78,9 +84,13 @@ func TaskerHandler(database database.Database, redis redis.Client) *fiber.App {
 	listAPI.Delete("/task", func(c *fiber.Ctx) error {
 		return list.TaskFinished(c)
 	})
+	listAPI.Delete("/task/:uuid", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return list.DeleteTask(uuid, c)
+	})
 
 	// Reminders
-	reminder := controllers.InitReminderController(database)
+	reminder := controllers.InitReminderController(database, queue)
 	listAPI.Get("/reminders", func(c *fiber.Ctx) error {
 		return reminder.GetAllReminders(c)
 	})
@@ -92,6 +102,56 @@ func TaskerHandler(database database.Database, redis redis.Client) *fiber.App {
 		uuid := utils.ParseUUID(c.Params("uuid"))
 		return reminder.GetReminderByTaskUuid(uuid, c)
 	})
+	listAPI.Get("/reminder/:uuid/deliveries", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return reminder.GetReminderDeliveries(uuid, c)
+	})
+	listAPI.Get("/reminder/:uuid/occurrences", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return reminder.GetReminderOccurrences(uuid, c)
+	})
+	listAPI.Post("/reminder/occurrences", func(c *fiber.Ctx) error {
+		return reminder.PreviewReminderOccurrences(c)
+	})
+	listAPI.Post("/reminder/:uuid/snooze", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return reminder.SnoozeReminder(uuid, c)
+	})
+	listAPI.Post("/reminder/:uuid/acknowledge", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return reminder.AcknowledgeReminder(uuid, c)
+	})
+	listAPI.Get("/reminder/:uuid/escalation", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return reminder.GetReminderEscalation(uuid, c)
+	})
+	listAPI.Put("/reminder/:uuid/escalation", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return reminder.SetReminderEscalation(uuid, c)
+	})
+	listAPI.Get("/task/:uuid/reminders", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return reminder.GetTaskReminders(uuid, c)
+	})
+	listAPI.Post("/task/:uuid/reminders", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return reminder.AddTaskReminder(uuid, c)
+	})
+	listAPI.Post("/task/:uuid/reminders/:reminder/pause", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		reminderUuid := utils.ParseUUID(c.Params("reminder"))
+		return reminder.PauseReminder(uuid, reminderUuid, c)
+	})
+	listAPI.Post("/task/:uuid/reminders/:reminder/resume", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		reminderUuid := utils.ParseUUID(c.Params("reminder"))
+		return reminder.ResumeReminder(uuid, reminderUuid, c)
+	})
+	listAPI.Delete("/task/:uuid/reminders/:reminder", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		reminderUuid := utils.ParseUUID(c.Params("reminder"))
+		return reminder.DeleteReminder(uuid, reminderUuid, c)
+	})
 	listAPI.Post("/reminder", func(c *fiber.Ctx) error {
 		return reminder.CreateRemainder(c)
 	})
@@ -99,5 +159,76 @@ func TaskerHandler(database database.Database, redis redis.Client) *fiber.App {
 		return reminder.UpdateRemainder(c)
 	})
 
+	// Quiet hours
+	quietHours := controllers.InitQuietHoursController(database)
+	listAPI.Get("/quiet-hours", func(c *fiber.Ctx) error {
+		return quietHours.GetAllQuietHours(c)
+	})
+	listAPI.Post("/quiet-hours", func(c *fiber.Ctx) error {
+		return quietHours.CreateQuietHours(c)
+	})
+	listAPI.Delete("/quiet-hours/:uuid", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return quietHours.DeleteQuietHours(uuid, c)
+	})
+
+	// Coalescing windows
+	coalesceWindow := controllers.InitCoalesceWindowController(database)
+	listAPI.Get("/coalesce-windows", func(c *fiber.Ctx) error {
+		return coalesceWindow.GetAllCoalesceWindows(c)
+	})
+	listAPI.Post("/coalesce-windows", func(c *fiber.Ctx) error {
+		return coalesceWindow.CreateCoalesceWindow(c)
+	})
+	listAPI.Delete("/coalesce-windows/:uuid", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return coalesceWindow.DeleteCoalesceWindow(uuid, c)
+	})
+
+	// Exclusion calendars
+	calendar := controllers.InitCalendarController(database, queue)
+	listAPI.Get("/calendars", func(c *fiber.Ctx) error {
+		return calendar.GetAllCalendars(c)
+	})
+	listAPI.Get("/calendar/:name", func(c *fiber.Ctx) error {
+		return calendar.GetCalendar(c.Params("name"), c)
+	})
+	listAPI.Post("/calendar", func(c *fiber.Ctx) error {
+		return calendar.CreateCalendar(c)
+	})
+	listAPI.Post("/calendar/:name/dates", func(c *fiber.Ctx) error {
+		return calendar.AddCalendarDates(c.Params("name"), c)
+	})
+	listAPI.Post("/calendar/:name/import", func(c *fiber.Ctx) error {
+		return calendar.ImportCalendar(c.Params("name"), c)
+	})
+	listAPI.Delete("/calendar/:name", func(c *fiber.Ctx) error {
+		return calendar.DeleteCalendar(c.Params("name"), c)
+	})
+
+	// Dead letters
+	deadLetter := controllers.InitDeadLetterController(database)
+	listAPI.Get("/dead-letters", func(c *fiber.Ctx) error {
+		return deadLetter.GetDeadLetters(c)
+	})
+	listAPI.Get("/dead-letter/:uuid", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return deadLetter.GetDeadLetter(uuid, c)
+	})
+	listAPI.Post("/dead-letters/retry", func(c *fiber.Ctx) error {
+		return deadLetter.RetryDeadLetters(c)
+	})
+	listAPI.Post("/dead-letter/:uuid/retry", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return deadLetter.RetryDeadLetter(uuid, c)
+	})
+	listAPI.Delete("/dead-letters", func(c *fiber.Ctx) error {
+		return deadLetter.PurgeDeadLetters(c)
+	})
+	listAPI.Delete("/dead-letter/:uuid", func(c *fiber.Ctx) error {
+		uuid := utils.ParseUUID(c.Params("uuid"))
+		return deadLetter.DeleteDeadLetter(uuid, c)
+	})
+
 	return app
 }
@@ -4,14 +4,15 @@ import (
 	"fmt"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gen2brain/beeep"
 	"github.com/google/uuid"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/kevinhartarto/tasker/internal/logger"
 	"github.com/kevinhartarto/tasker/internal/models"
+	"github.com/kevinhartarto/tasker/internal/recurrence"
 )
 
 var log = logger.GetLogger()
@@ -24,6 +25,17 @@ func GetEnvOrDefault(envName string, defaultValue string) any {
 	return defaultValue
 }
 
+func GetEnvIntOrDefault(envName string, defaultValue int) int {
+	if envValue := os.Getenv(envName); envValue != "" {
+		if value, err := strconv.Atoi(envValue); err == nil {
+			return value
+		}
+		log.Info("Invalid number in environment, using default", "env", envName, "value", envValue)
+	}
+
+	return defaultValue
+}
+
 func GenerateNewUUID() uuid.UUID {
 	newUUID, err := uuid.NewUUID()
 
@@ -73,6 +85,39 @@ func ValidateReminder(reminder models.Reminder) bool {
 		return false
 	}
 
+	// time zone must be a known IANA zone
+	if _, err := recurrence.Location(reminder); err != nil {
+		return false
+	}
+
+	// catch up policy defaults to the latest missed occurrence
+	if !slices.Contains([]string{"", models.CatchUpAll, models.CatchUpLatest, models.CatchUpSkip}, reminder.CatchUp) {
+		return false
+	}
+
+	// calendar must exist, excluded occurrences are skipped by default
+	if reminder.Calendar != "" && !recurrence.HasCalendar(reminder.Calendar) {
+		return false
+	}
+	if !slices.Contains([]string{"", models.CalendarPolicySkip, models.CalendarPolicyNext, models.CalendarPolicyPrevious}, reminder.CalendarPolicy) {
+		return false
+	}
+
+	// business day of the month is only used with the "b" frequency
+	if reminder.BusinessDay != nil && reminder.Frequency != "b" {
+		return false
+	}
+
+	// cron expression is only used with the "c" frequency
+	if reminder.Cron != "" && reminder.Frequency != "c" {
+		return false
+	}
+
+	// recurrence rule is only used with the "r" frequency
+	if reminder.RRule != "" && reminder.Frequency != "r" {
+		return false
+	}
+
 	if reminder.Frequency == "" {
 		return false
 	} else {
@@ -98,9 +143,12 @@ func ValidateReminder(reminder models.Reminder) bool {
 			return ValidateNextReminder(reminder)
 		case "s":
 			days := []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
-			valid := false
+			// every repeat day must be known, at least one is required
+			valid := len(reminder.RepeatDays) > 0
 			for _, day := range reminder.RepeatDays {
-				valid = slices.Contains(days, day)
+				if !slices.Contains(days, day) {
+					valid = false
+				}
 			}
 
 			if !validateFrequenctTypeRepeats(reminder) || !valid {
@@ -108,6 +156,48 @@ func ValidateReminder(reminder models.Reminder) bool {
 			}
 			// check next reminder
 			return ValidateNextReminder(reminder)
+		// RFC 5545 recurrence rule
+		case "r":
+			if _, err := recurrence.ParseRule(reminder); err != nil {
+				return false
+			}
+			// check next reminder
+			return ValidateNextReminder(reminder)
+		// Cron expression, 5 or 6 fields with an optional time zone
+		case "c":
+			if reminder.Interval != nil || reminder.IntervalInMinutes != nil {
+				return false
+			}
+			if _, err := recurrence.ParseCron(reminder); err != nil {
+				return false
+			}
+			// check next reminder
+			return ValidateNextReminder(reminder)
+		// Business days of the reminder calendar,
+		// every Interval days or the n-th of every Interval months
+		case "b":
+			if reminder.Interval == nil ||
+				*reminder.Interval <= 0 ||
+				reminder.RepeatSameday ||
+				reminder.IntervalInMinutes != nil {
+				return false
+			}
+			// at most 23 business days fit a month, negative from the month end
+			if reminder.BusinessDay != nil && (*reminder.BusinessDay < -23 || *reminder.BusinessDay > 23) {
+				return false
+			}
+			// check next reminder
+			return ValidateNextReminder(reminder)
+		// Working hours of the reminder calendar
+		case "h":
+			if reminder.Interval != nil ||
+				reminder.IntervalInMinutes == nil ||
+				*reminder.IntervalInMinutes <= 0 ||
+				*reminder.IntervalInMinutes >= 1440 {
+				return false
+			}
+			// check next reminder
+			return ValidateNextReminder(reminder)
 		default:
 			break
 		}
@@ -119,35 +209,29 @@ func validateFrequenctTypeRepeats(reminder models.Reminder) bool {
 	if reminder.Interval == nil ||
 		reminder.RepeatSameday ||
 		reminder.IntervalInMinutes != nil ||
+		reminder.RepeatUntil == nil ||
 		reminder.RepeatUntil.IsZero() ||
-		reminder.NextReminder.IsZero() {
+		reminder.NextReminder == nil {
 		return false
 	}
 	return true
 }
 
 func ValidateNextReminder(reminder models.Reminder) bool {
-	expectedDate := reminder.StartTime
-
-	switch reminder.Frequency {
-	case "n":
-		expectedDate = expectedDate.Add(time.Minute * time.Duration(*reminder.IntervalInMinutes))
-	case "d", "w", "m", "y":
-		expectedDate = expectedDate.Add(time.Duration(*reminder.Interval))
-	case "s":
-		// get next reminder week day
-		nextDateWeekday := strings.ToLower(reminder.NextReminder.Weekday().String())
-
-		// compare week day with repeat days
-		if !slices.Contains(reminder.RepeatDays, nextDateWeekday[0:3]) &&
-			(reminder.NextReminder.After(*reminder.RepeatUntil)) {
-			return false
-		}
+	if reminder.NextReminder == nil {
+		return false
+	}
 
-		return true
+	// Next reminder must fall within the schedule
+	if reminder.NextReminder.Before(reminder.StartTime) {
+		return false
+	}
+	if reminder.RepeatUntil != nil && !reminder.RepeatUntil.IsZero() && reminder.NextReminder.After(*reminder.RepeatUntil) {
+		return false
 	}
 
-	return (reminder.NextReminder.Equal(expectedDate)) && (reminder.NextReminder.Before(*reminder.RepeatUntil))
+	// and never on a date excluded by the reminder calendar
+	return !recurrence.Excluded(reminder, *reminder.NextReminder)
 }
 
 func SendDesktopNotification(level string, title string, msgBody string) {
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Version of the ReminderAck schema, versioned apart
+// from ReminderSchemaVersion as acknowledgements come from other services
+const AckSchemaVersion = 1
+
+// Event types reported back to tasker
+const (
+	ReminderAckType = "reminder.ack"
+)
+
+// Statuses of a ReminderAck
+const (
+	AckDelivered = "delivered"
+	AckRead      = "read"
+	AckSnoozed   = "snoozed"
+	AckFailed    = "failed"
+)
+
+// ReminderAck is consumed from the tasker_reminder_ack topic,
+// published by downstream services about a ReminderFired they handled:
+//
+//	{
+//	  "schema_version": 1,
+//	  "type": "reminder.ack",
+//	  "event_id": "5b0e5a0c-...",
+//	  "occurrence_id": "5b0e5a0c-...",
+//	  "reminder_id": "0f8fad5b-...",
+//	  "channel": "kafka",
+//	  "status": "snoozed",
+//	  "snoozed_until": "2025-03-01T10:00:00+01:00",
+//	  "reported_at": "2025-03-01T09:00:05+01:00"
+//	}
+//
+// Read acknowledges the occurrence, snoozed needs snoozed_until
+// and failed has tasker send the event again.
+type ReminderAck struct {
+	SchemaVersion int        `json:"schema_version"`
+	Type          string     `json:"type"`
+	EventId       uuid.UUID  `json:"event_id"`
+	OccurrenceId  uuid.UUID  `json:"occurrence_id"`
+	ReminderId    uuid.UUID  `json:"reminder_id"`
+	Channel       string     `json:"channel"`
+	Status        string     `json:"status"`
+	SnoozedUntil  *time.Time `json:"snoozed_until,omitempty"`
+	Error         string     `json:"error,omitempty"`
+	ReportedAt    time.Time  `json:"reported_at"`
+}
@@ -0,0 +1,128 @@
+// Package events holds the messages tasker publishes for other services.
+//
+// Every reminder occurrence is published as a ReminderFired JSON document,
+// keyed by task id on the tasker_reminder_notify topic:
+//
+//	{
+//	  "schema_version": 1,
+//	  "type": "reminder.fired",
+//	  "event_id": "5b0e5a0c-...",
+//	  "occurrence_id": "5b0e5a0c-...",
+//	  "reminder_id": "0f8fad5b-...",
+//	  "task_id": "7c9e6679-...",
+//	  "task": "Renew certificates",
+//	  "reminder": "1 day before",
+//	  "description": "Renew *.example.com",
+//	  "message": "1 day before: Renew certificates - Renew *.example.com",
+//	  "recipient": "ops@example.com",
+//	  "occurrence": "2025-03-01T09:00:00+01:00",
+//	  "channels": ["kafka", "webhook"],
+//	  "snooze_count": 1,
+//	  "escalation": 1,
+//	  "level": "alert",
+//	  "sent_at": "2025-03-01T09:00:02+01:00"
+//	}
+//
+// The message is rendered from the reminder template of the channel
+// the event is published on, so it differs between channels.
+//
+// An occurrence fired again after a snooze keeps its occurrence_id
+// and reports how many times it was snoozed, with its own event_id.
+// Escalations of an unacknowledged occurrence do the same and report
+// the escalation step taken and the notification level to use.
+//
+// Reminders for the same recipient and channel firing within a coalescing
+// window are merged into one reminder.digest event instead, with the
+// reminder.fired events in items and the message rendered from the digest
+// template. A digest has no reminder, task or occurrence of its own,
+// consumers acknowledge the event_id of every item. Digests carry their own
+// schema version and are opt in: coalescing windows are off unless
+// configured, and on Kafka digests go to their own topic,
+// tasker_reminder_digest keyed by recipient, never to the reminder topic.
+//
+// Kafka messages also carry the content-type, correlation-id,
+// schema-version and event-type headers. Consumers should ignore
+// unknown fields and check schema_version before reading the body.
+package events
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Version of the ReminderFired schema,
+// bumped on every breaking change
+const ReminderSchemaVersion = 1
+
+// Version of the reminder digest schema, versioned apart
+// from ReminderSchemaVersion as digests are published apart
+const DigestSchemaVersion = 1
+
+// Event types
+const (
+	ReminderFiredType  = "reminder.fired"
+	ReminderDigestType = "reminder.digest"
+)
+
+// Kafka header names and values
+const (
+	HeaderContentType   = "content-type"
+	HeaderCorrelationId = "correlation-id"
+	HeaderSchemaVersion = "schema-version"
+	HeaderEventType     = "event-type"
+
+	ContentTypeJSON = "application/json"
+)
+
+// ReminderFired is published every time a reminder occurrence fires,
+// or once for a digest of occurrences
+type ReminderFired struct {
+	SchemaVersion int       `json:"schema_version"`
+	Type          string    `json:"type"`
+	EventId       uuid.UUID `json:"event_id"`
+	OccurrenceId  uuid.UUID `json:"occurrence_id"`
+	ReminderId    uuid.UUID `json:"reminder_id"`
+	TaskId        uuid.UUID `json:"task_id"`
+	Task          string    `json:"task"`
+	Reminder      string    `json:"reminder"`
+	Description   string    `json:"description"`
+	Message       string    `json:"message,omitempty"`
+	Recipient     string    `json:"recipient,omitempty"`
+	Occurrence    time.Time `json:"occurrence"`
+	Channels      []string  `json:"channels"`
+	SnoozeCount   int       `json:"snooze_count,omitempty"`
+	Escalation    int       `json:"escalation,omitempty"`
+	Level         string    `json:"level,omitempty"`
+	SentAt        time.Time `json:"sent_at"`
+
+	Items []ReminderFired `json:"items,omitempty"`
+}
+
+// OccurrenceId identifies a single occurrence of a reminder,
+// the same occurrence always gets the same id so consumers can deduplicate
+func OccurrenceId(reminderId uuid.UUID, occurrence time.Time) uuid.UUID {
+	return uuid.NewSHA1(reminderId, []byte(occurrence.UTC().Format(time.RFC3339Nano)))
+}
+
+// SnoozeId identifies an occurrence fired again after its n-th snooze
+func SnoozeId(occurrenceId uuid.UUID, snoozeCount int) uuid.UUID {
+	return uuid.NewSHA1(occurrenceId, []byte(fmt.Sprintf("snooze-%d", snoozeCount)))
+}
+
+// EscalationId identifies the n-th escalation of a fired event
+func EscalationId(eventId uuid.UUID, escalation int) uuid.UUID {
+	return uuid.NewSHA1(eventId, []byte(fmt.Sprintf("escalation-%d", escalation)))
+}
+
+// DigestId identifies a digest of fired events,
+// the same events always get the same id so consumers can deduplicate
+func DigestId(eventIds []uuid.UUID) uuid.UUID {
+	var name []byte
+	for _, eventId := range eventIds {
+		name = append(name, eventId[:]...)
+	}
+
+	return uuid.NewSHA1(uuid.NameSpaceOID, name)
+}